@@ -0,0 +1,222 @@
+package pn532
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Transceiver 可以与ISO/IEC14443-4卡片交换数据的通道
+// *Pn532(通过InDataExchange由固件处理协议) 与 *IsoDep(软件实现协议) 都满足该接口
+type Transceiver interface {
+	Transceive(data []byte) ([]byte, error)
+}
+
+// claChaining CLA中的命令链标志
+const claChaining byte = 0x10
+
+// CommandAPDU ISO/IEC7816-4 命令APDU
+type CommandAPDU struct {
+	CLA  byte
+	INS  byte
+	P1   byte
+	P2   byte
+	Data []byte
+	// Ne 期望的响应数据长度 0表示没有响应数据
+	// 短APDU最大为256 扩展APDU最大为65536 超过该值按最大值编码
+	Ne int
+	// Extended 使用扩展长度编码 为false时超过255字节的数据使用命令链发送
+	Extended bool
+}
+
+// Bytes 编码命令APDU 根据数据长度与Ne自动选择短或扩展编码
+func (c *CommandAPDU) Bytes() ([]byte, error) {
+	if c.Ne < 0 {
+		return nil, errors.New("ne must not be negative")
+	}
+	if len(c.Data) > 65535 {
+		return nil, errors.New("data too long")
+	}
+	buf := []byte{c.CLA, c.INS, c.P1, c.P2}
+	extended := c.Extended || len(c.Data) > 255 || c.Ne > 256
+	if !extended {
+		if len(c.Data) > 0 {
+			buf = append(buf, byte(len(c.Data)))
+			buf = append(buf, c.Data...)
+		}
+		if c.Ne > 0 {
+			buf = append(buf, byte(c.Ne)) // 256 编码为 0x00
+		}
+		return buf, nil
+	}
+	ne := c.Ne
+	if ne > 65536 {
+		ne = 65536
+	}
+	if len(c.Data) > 0 {
+		buf = append(buf, 0x00, byte(len(c.Data)>>8), byte(len(c.Data)))
+		buf = append(buf, c.Data...)
+		if ne > 0 {
+			buf = append(buf, byte(ne>>8), byte(ne)) // 65536 编码为 0x0000
+		}
+	} else if ne > 0 {
+		buf = append(buf, 0x00, byte(ne>>8), byte(ne))
+	}
+	return buf, nil
+}
+
+// ResponseAPDU ISO/IEC7816-4 响应APDU
+type ResponseAPDU struct {
+	Data []byte
+	SW1  byte
+	SW2  byte
+}
+
+// ParseResponseAPDU 解析响应APDU 最后两个字节为状态字
+func ParseResponseAPDU(raw []byte) (*ResponseAPDU, error) {
+	if len(raw) < 2 {
+		return nil, errors.New("response apdu too short")
+	}
+	return &ResponseAPDU{
+		Data: append([]byte(nil), raw[:len(raw)-2]...),
+		SW1:  raw[len(raw)-2],
+		SW2:  raw[len(raw)-1],
+	}, nil
+}
+
+// SW 状态字
+func (r *ResponseAPDU) SW() uint16 {
+	return uint16(r.SW1)<<8 | uint16(r.SW2)
+}
+
+// OK 状态字是否为9000
+func (r *ResponseAPDU) OK() bool {
+	return r.SW() == 0x9000
+}
+
+// Err 状态字不是9000时返回 SWError
+func (r *ResponseAPDU) Err() error {
+	if r.OK() {
+		return nil
+	}
+	return SWError(r.SW())
+}
+
+// Bytes 编码响应APDU
+func (r *ResponseAPDU) Bytes() []byte {
+	return append(append([]byte(nil), r.Data...), r.SW1, r.SW2)
+}
+
+// SWError 非9000的状态字
+type SWError uint16
+
+var swMessages = map[SWError]string{
+	0x6281: "part of returned data may be corrupted",
+	0x6282: "end of file reached before reading Ne bytes",
+	0x6700: "wrong length",
+	0x6881: "logical channel not supported",
+	0x6882: "secure messaging not supported",
+	0x6982: "security status not satisfied",
+	0x6983: "authentication method blocked",
+	0x6984: "reference data not usable",
+	0x6985: "conditions of use not satisfied",
+	0x6986: "command not allowed",
+	0x6A80: "incorrect parameters in the data field",
+	0x6A81: "function not supported",
+	0x6A82: "file or application not found",
+	0x6A83: "record not found",
+	0x6A84: "not enough memory space in the file",
+	0x6A86: "incorrect parameters P1-P2",
+	0x6A88: "referenced data not found",
+	0x6B00: "wrong parameters P1-P2",
+	0x6D00: "instruction code not supported or invalid",
+	0x6E00: "class not supported",
+	0x6F00: "no precise diagnosis",
+}
+
+func (e SWError) Error() string {
+	if msg, ok := swMessages[e]; ok {
+		return fmt.Sprintf("sw %04X: %s", uint16(e), msg)
+	}
+	if e&0xFFF0 == 0x63C0 {
+		return fmt.Sprintf("sw %04X: verification failed, %d retries left", uint16(e), e&0x0F)
+	}
+	return fmt.Sprintf("sw %04X", uint16(e))
+}
+
+// Transmit 向当前选择的ISO/IEC14443-4目标发送APDU
+func (p *Pn532) Transmit(c *CommandAPDU) (*ResponseAPDU, error) {
+	switch t := p.current.(type) {
+	case *TargetA:
+		if !t.ISO14443_4() {
+			return nil, errors.New("target is not ISO/IEC14443-4 compliant")
+		}
+	case *TargetB:
+	default:
+		return nil, ErrNoTarget
+	}
+	return TransmitAPDU(p, c)
+}
+
+// TransmitAPDU 通过tr发送APDU
+// 数据超过255字节且没有使用扩展编码时使用命令链(CLA bit 0x10)发送
+// 自动处理61xx(GET RESPONSE取回剩余数据)与6Cxx(使用正确的Le重发)
+func TransmitAPDU(tr Transceiver, c *CommandAPDU) (*ResponseAPDU, error) {
+	cmd := *c
+	if !cmd.Extended && len(cmd.Data) > 255 {
+		data := cmd.Data
+		for len(data) > 255 {
+			part := CommandAPDU{CLA: cmd.CLA | claChaining, INS: cmd.INS, P1: cmd.P1, P2: cmd.P2, Data: data[:255]}
+			resp, err := transmitOnce(tr, &part)
+			if err != nil {
+				return nil, err
+			}
+			if !resp.OK() {
+				return resp, nil
+			}
+			data = data[255:]
+		}
+		cmd.Data = data
+	}
+
+	resp, err := transmitOnce(tr, &cmd)
+	if err != nil {
+		return nil, err
+	}
+	if resp.SW1 == 0x6C {
+		// Le错误 使用卡片给出的长度重发
+		cmd.Ne = int(resp.SW2)
+		if cmd.Ne == 0 {
+			cmd.Ne = 256
+		}
+		if resp, err = transmitOnce(tr, &cmd); err != nil {
+			return nil, err
+		}
+	}
+
+	data := resp.Data
+	for resp.SW1 == 0x61 {
+		ne := int(resp.SW2)
+		if ne == 0 {
+			ne = 256
+		}
+		getResponse := CommandAPDU{CLA: cmd.CLA &^ claChaining, INS: 0xC0, Ne: ne}
+		if resp, err = transmitOnce(tr, &getResponse); err != nil {
+			return nil, err
+		}
+		data = append(data, resp.Data...)
+	}
+	resp.Data = data
+	return resp, nil
+}
+
+func transmitOnce(tr Transceiver, c *CommandAPDU) (*ResponseAPDU, error) {
+	raw, err := c.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := tr.Transceive(raw)
+	if err != nil {
+		return nil, err
+	}
+	return ParseResponseAPDU(resp)
+}
@@ -0,0 +1,111 @@
+package pn532
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestAPDUEncoding(t *testing.T) {
+	for _, tc := range []struct {
+		c    CommandAPDU
+		want []byte
+	}{
+		{CommandAPDU{CLA: 0x00, INS: 0xA4, P1: 0x04, P2: 0x00}, []byte{0x00, 0xA4, 0x04, 0x00}},
+		{CommandAPDU{INS: 0xB0, Ne: 256}, []byte{0x00, 0xB0, 0x00, 0x00, 0x00}},
+		{CommandAPDU{INS: 0xA4, P1: 0x04, Data: []byte{0xA0, 0x00}, Ne: 0x10}, []byte{0x00, 0xA4, 0x04, 0x00, 0x02, 0xA0, 0x00, 0x10}},
+		{CommandAPDU{INS: 0xB0, Ne: 65536}, []byte{0x00, 0xB0, 0x00, 0x00, 0x00, 0x00, 0x00}},
+		{CommandAPDU{INS: 0xD6, Data: []byte{0x01}, Ne: 0x0200}, []byte{0x00, 0xD6, 0x00, 0x00, 0x00, 0x00, 0x01, 0x01, 0x02, 0x00}},
+		{CommandAPDU{INS: 0xD6, Data: []byte{0x01}, Extended: true}, []byte{0x00, 0xD6, 0x00, 0x00, 0x00, 0x00, 0x01, 0x01}},
+	} {
+		got, err := tc.c.Bytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, tc.want) {
+			t.Errorf("unexpected encoding: % X, want % X", got, tc.want)
+		}
+	}
+}
+
+func TestResponseAPDU(t *testing.T) {
+	if _, err := ParseResponseAPDU([]byte{0x90}); err == nil {
+		t.Error("short response accepted")
+	}
+	r, err := ParseResponseAPDU([]byte{0x01, 0x02, 0x90, 0x00})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.OK() || r.Err() != nil || !bytes.Equal(r.Data, []byte{0x01, 0x02}) {
+		t.Errorf("unexpected response: %+v", r)
+	}
+	r, _ = ParseResponseAPDU([]byte{0x6A, 0x82})
+	var sw SWError
+	if !errors.As(r.Err(), &sw) || sw != 0x6A82 {
+		t.Errorf("unexpected error: %v", r.Err())
+	}
+	if SWError(0x63C2).Error() != "sw 63C2: verification failed, 2 retries left" {
+		t.Errorf("unexpected message: %s", SWError(0x63C2))
+	}
+}
+
+// fakeCard 按顺序返回预设的响应 并记录收到的命令
+type fakeCard struct {
+	sent      [][]byte
+	responses [][]byte
+}
+
+func (c *fakeCard) Transceive(data []byte) ([]byte, error) {
+	c.sent = append(c.sent, append([]byte(nil), data...))
+	if len(c.responses) == 0 {
+		return nil, errors.New("no response")
+	}
+	resp := c.responses[0]
+	c.responses = c.responses[1:]
+	return resp, nil
+}
+
+func TestTransmitAPDU(t *testing.T) {
+	// 61xx
+	card := &fakeCard{responses: [][]byte{{0x01, 0x61, 0x02}, {0x02, 0x03, 0x90, 0x00}}}
+	r, err := TransmitAPDU(card, &CommandAPDU{INS: 0xCA, Ne: 256})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.OK() || !bytes.Equal(r.Data, []byte{0x01, 0x02, 0x03}) {
+		t.Errorf("unexpected response: %+v", r)
+	}
+	if !bytes.Equal(card.sent[1], []byte{0x00, 0xC0, 0x00, 0x00, 0x02}) {
+		t.Errorf("unexpected GET RESPONSE: % X", card.sent[1])
+	}
+
+	// 6Cxx
+	card = &fakeCard{responses: [][]byte{{0x6C, 0x04}, {0x01, 0x02, 0x03, 0x04, 0x90, 0x00}}}
+	r, err = TransmitAPDU(card, &CommandAPDU{INS: 0xB0, Ne: 0x10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Data) != 4 || !bytes.Equal(card.sent[1], []byte{0x00, 0xB0, 0x00, 0x00, 0x04}) {
+		t.Errorf("unexpected re-issue: % X", card.sent[1])
+	}
+
+	// 命令链
+	card = &fakeCard{responses: [][]byte{{0x90, 0x00}, {0x90, 0x00}}}
+	data := make([]byte, 300)
+	if _, err := TransmitAPDU(card, &CommandAPDU{CLA: 0x80, INS: 0xE8, Data: data}); err != nil {
+		t.Fatal(err)
+	}
+	if len(card.sent) != 2 || card.sent[0][0] != 0x90 || card.sent[0][4] != 0xFF || card.sent[1][0] != 0x80 || card.sent[1][4] != 45 {
+		t.Errorf("unexpected chaining: % X / % X", card.sent[0][:5], card.sent[1][:5])
+	}
+
+	// 链中途出错
+	card = &fakeCard{responses: [][]byte{{0x68, 0x84}}}
+	r, err = TransmitAPDU(card, &CommandAPDU{INS: 0xE8, Data: data})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.SW() != 0x6884 || len(card.sent) != 1 {
+		t.Errorf("chaining not aborted: %04X", r.SW())
+	}
+}
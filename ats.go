@@ -0,0 +1,150 @@
+package pn532
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// fsTable FSDI/FSCI 对应的帧长度
+var fsTable = [...]int{16, 24, 32, 40, 48, 64, 96, 128, 256}
+
+// frameSize 将FSDI/FSCI转换为帧长度 RFU的值按256处理
+func frameSize(fsi byte) int {
+	if int(fsi) >= len(fsTable) {
+		return 256
+	}
+	return fsTable[fsi]
+}
+
+// ATS Answer To Select
+type ATS struct {
+	Raw        []byte
+	FSCI       byte
+	TA         *byte // 接口字节 不存在时为nil
+	TB         *byte
+	TC         *byte
+	Historical []byte
+}
+
+// ParseATS 解析ATS 第一个字节TL为ATS的长度
+func ParseATS(raw []byte) (*ATS, error) {
+	if len(raw) < 1 || int(raw[0]) != len(raw) {
+		return nil, errors.New("invalid ATS length")
+	}
+	// 只有TL时 T0等均取默认值
+	ats := &ATS{Raw: append([]byte(nil), raw...), FSCI: 0x02}
+	if len(raw) == 1 {
+		return ats, nil
+	}
+	t0 := raw[1]
+	ats.FSCI = t0 & 0x0F
+	i := 2
+	for _, field := range []struct {
+		mask byte
+		dst  **byte
+	}{{0x10, &ats.TA}, {0x20, &ats.TB}, {0x40, &ats.TC}} {
+		if t0&field.mask == 0 {
+			continue
+		}
+		if i >= len(raw) {
+			return nil, errors.New("ATS interface bytes missing")
+		}
+		v := raw[i]
+		*field.dst = &v
+		i++
+	}
+	ats.Historical = append([]byte(nil), raw[i:]...)
+	return ats, nil
+}
+
+// FSC 卡片可接收的最大帧长度
+func (a *ATS) FSC() int {
+	return frameSize(a.FSCI)
+}
+
+// FWI 帧等待时间系数 TB不存在时默认为4 RFU的15同样按4处理
+func (a *ATS) FWI() byte {
+	if a.TB == nil || *a.TB>>4 == 0x0F {
+		return 0x04
+	}
+	return *a.TB >> 4
+}
+
+// FWT 帧等待时间 (256 * 16 / fc) * 2^FWI
+func (a *ATS) FWT() time.Duration {
+	return fwtUnit << a.FWI()
+}
+
+// SFGI 启动帧保护时间系数 TB不存在时默认为0
+func (a *ATS) SFGI() byte {
+	if a.TB == nil {
+		return 0x00
+	}
+	return *a.TB & 0x0F
+}
+
+// SFGT 启动帧保护时间 卡片发送ATS后需要等待的时间
+func (a *ATS) SFGT() time.Duration {
+	if a.SFGI() == 0x00 || a.SFGI() == 0x0F {
+		return 0
+	}
+	return fwtUnit << a.SFGI()
+}
+
+// bitRates 根据TA中的3位标志返回支持的速率 106 kbps总是支持
+func bitRates(flags byte) []int {
+	rates := []int{106}
+	for i, rate := range []int{212, 424, 848} {
+		if flags&(0x01<<i) != 0 {
+			rates = append(rates, rate)
+		}
+	}
+	return rates
+}
+
+// BitRatesPICCToPCD 卡片到读卡器方向支持的速率(kbps) 对应TA中的DS
+func (a *ATS) BitRatesPICCToPCD() []int {
+	if a.TA == nil {
+		return []int{106}
+	}
+	return bitRates(*a.TA >> 4 & 0x07)
+}
+
+// BitRatesPCDToPICC 读卡器到卡片方向支持的速率(kbps) 对应TA中的DR
+func (a *ATS) BitRatesPCDToPICC() []int {
+	if a.TA == nil {
+		return []int{106}
+	}
+	return bitRates(*a.TA & 0x07)
+}
+
+// SameBitRateRequired 卡片是否要求两个方向使用相同的速率
+func (a *ATS) SameBitRateRequired() bool {
+	return a.TA != nil && *a.TA&0x80 != 0
+}
+
+// NADSupported 卡片是否支持NAD
+func (a *ATS) NADSupported() bool {
+	return a.TC != nil && *a.TC&0x01 != 0
+}
+
+// CIDSupported 卡片是否支持CID TC不存在时默认支持
+func (a *ATS) CIDSupported() bool {
+	return a.TC == nil || *a.TC&0x02 != 0
+}
+
+func (a *ATS) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "FSC: %d, FWT: %s, SFGT: %s", a.FSC(), a.FWT(), a.SFGT())
+	fmt.Fprintf(&b, ", PICC->PCD: %v kbps, PCD->PICC: %v kbps", a.BitRatesPICCToPCD(), a.BitRatesPCDToPICC())
+	if a.SameBitRateRequired() {
+		b.WriteString(" (same bit rate required)")
+	}
+	fmt.Fprintf(&b, ", CID: %t, NAD: %t", a.CIDSupported(), a.NADSupported())
+	if len(a.Historical) > 0 {
+		fmt.Fprintf(&b, ", historical bytes: % X", a.Historical)
+	}
+	return b.String()
+}
@@ -0,0 +1,43 @@
+package pn532
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseATS(t *testing.T) {
+	ats, err := ParseATS([]byte{0x06, 0x75, 0x77, 0x81, 0x02, 0x80})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if ats.FSC() != 64 || ats.FWI() != 0x08 || ats.SFGI() != 0x01 || !ats.CIDSupported() || ats.NADSupported() {
+		t.Errorf("unexpected ATS: %#v", ats)
+	}
+	if !bytes.Equal(ats.Historical, []byte{0x80}) {
+		t.Errorf("unexpected historical bytes: % X", ats.Historical)
+	}
+	if !reflect.DeepEqual(ats.BitRatesPICCToPCD(), []int{106, 212, 424, 848}) ||
+		!reflect.DeepEqual(ats.BitRatesPCDToPICC(), []int{106, 212, 424, 848}) || ats.SameBitRateRequired() {
+		t.Errorf("unexpected bit rates: %s", ats)
+	}
+	if ats.FWT() != 302*time.Microsecond<<8 || ats.SFGT() != 604*time.Microsecond {
+		t.Errorf("unexpected timings: %s", ats)
+	}
+	if _, err := ParseATS([]byte{0x05, 0x75}); err == nil {
+		t.Error("expect error for invalid length")
+	}
+}
+
+func TestParseATSDefaults(t *testing.T) {
+	ats, err := ParseATS([]byte{0x01})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if ats.FSC() != 32 || ats.FWI() != 0x04 || ats.SFGT() != 0 || !reflect.DeepEqual(ats.BitRatesPCDToPICC(), []int{106}) {
+		t.Errorf("unexpected default ATS: %s", ats)
+	}
+}
@@ -0,0 +1,116 @@
+package pn532
+
+import (
+	"errors"
+	"fmt"
+)
+
+// InAutoPoll 的目标类型
+const (
+	PollTypeGeneric106    byte = 0x00 // Generic passive 106 kbps (ISO/IEC14443-4A, Mifare and DEP)
+	PollTypeGeneric212    byte = 0x01 // Generic passive 212 kbps (FeliCa and DEP)
+	PollTypeGeneric424    byte = 0x02 // Generic passive 424 kbps (FeliCa and DEP)
+	PollTypeISO14443B     byte = 0x03 // Passive 106 kbps ISO/IEC14443-4B
+	PollTypeJewel         byte = 0x04 // Innovision Jewel tag
+	PollTypeMifare        byte = 0x10 // Mifare card
+	PollTypeFeliCa212     byte = 0x11 // FeliCa 212 kbps card
+	PollTypeFeliCa424     byte = 0x12 // FeliCa 424 kbps card
+	PollTypeISO14443A4    byte = 0x20 // Passive 106 kbps ISO/IEC14443-4A
+	PollTypeISO14443B4    byte = 0x23 // Passive 106 kbps ISO/IEC14443-4B
+	PollTypeDEPPassive106 byte = 0x40 // DEP passive 106 kbps
+	PollTypeDEPPassive212 byte = 0x41 // DEP passive 212 kbps
+	PollTypeDEPPassive424 byte = 0x42 // DEP passive 424 kbps
+	PollTypeDEPActive106  byte = 0x80 // DEP active 106 kbps
+	PollTypeDEPActive212  byte = 0x81 // DEP active 212 kbps
+	PollTypeDEPActive424  byte = 0x82 // DEP active 424 kbps
+)
+
+// PolledTarget InAutoPoll 轮询到的目标 Type 为 PollType* 之一
+type PolledTarget struct {
+	Type byte
+	Target
+}
+
+// TargetDEP DEP目标 BrTy为对应速率的调制类型
+type TargetDEP struct {
+	targetBase
+	Active       bool
+	NFCID3       [10]byte
+	DID          byte
+	BS           byte // Send-bit rate supported by the target
+	BR           byte // Receive-bit rate supported by the target
+	TO           byte // Timeout value
+	PP           byte // Optional parameters
+	GeneralBytes []byte
+}
+
+func (t *TargetDEP) ID() []byte {
+	return t.NFCID3[:]
+}
+
+// decodeAutoPollResp 解析 NbTg [Type1 Len1 TargetData1] [Type2 Len2 TargetData2]
+func decodeAutoPollResp(resp []byte) ([]PolledTarget, error) {
+	if len(resp) == 0 {
+		return nil, errors.New("command resp error")
+	}
+	targets := make([]PolledTarget, 0, resp[0])
+	data := resp[1:]
+	for i := 0; i < int(resp[0]); i++ {
+		if len(data) < 2 || len(data) < 2+int(data[1]) {
+			return nil, errors.New("auto poll target data too short")
+		}
+		pollType, targetData := data[0], data[2:2+int(data[1])]
+		t, err := decodeAutoPollTarget(pollType, targetData)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, PolledTarget{Type: pollType, Target: t})
+		data = data[2+len(targetData):]
+	}
+	return targets, nil
+}
+
+func decodeAutoPollTarget(pollType byte, data []byte) (Target, error) {
+	var brTy byte
+	switch pollType {
+	case PollTypeGeneric106, PollTypeMifare, PollTypeISO14443A4:
+		brTy = ISO14443A
+	case PollTypeGeneric212, PollTypeFeliCa212:
+		brTy = FeliCa212
+	case PollTypeGeneric424, PollTypeFeliCa424:
+		brTy = FeliCa424
+	case PollTypeISO14443B, PollTypeISO14443B4:
+		brTy = ISO14443B
+	case PollTypeJewel:
+		brTy = Jewel
+	case PollTypeDEPPassive106, PollTypeDEPPassive212, PollTypeDEPPassive424,
+		PollTypeDEPActive106, PollTypeDEPActive212, PollTypeDEPActive424:
+		return decodeDEPTarget(pollType, data)
+	default:
+		return nil, fmt.Errorf("unknown poll type %#X", pollType)
+	}
+	// 数据长度已由Len给出 ATS是否存在可以直接根据剩余长度判断
+	t, _, err := decodeTargetData(brTy, data, true)
+	return t, err
+}
+
+// decodeDEPTarget 解析 Tg NFCID3t(10) DIDt BSt BRt TO PPt [Gt]
+func decodeDEPTarget(pollType byte, data []byte) (Target, error) {
+	if len(data) < 16 {
+		return nil, errors.New("DEP target data too short")
+	}
+	t := &TargetDEP{
+		targetBase: targetBase{tg: data[0], brTy: [...]byte{ISO14443A, FeliCa212, FeliCa424}[pollType&0x0F]},
+		Active:     pollType&0x80 != 0,
+		DID:        data[11],
+		BS:         data[12],
+		BR:         data[13],
+		TO:         data[14],
+		PP:         data[15],
+	}
+	copy(t.NFCID3[:], data[1:11])
+	if len(data) > 16 {
+		t.GeneralBytes = append([]byte(nil), data[16:]...)
+	}
+	return t, nil
+}
@@ -0,0 +1,51 @@
+package pn532
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDecodeAutoPollResp(t *testing.T) {
+	resp := []byte{
+		0x02,
+		// Mifare 1K
+		PollTypeMifare, 0x09, 0x01, 0x00, 0x04, 0x08, 0x04, 0xDE, 0xAD, 0xBE, 0xEF,
+		// FeliCa 212
+		PollTypeFeliCa212, 0x13, 0x02, 0x12, 0x01,
+		0x01, 0x2E, 0x4C, 0xA5, 0x36, 0x12, 0x34, 0x56,
+		0x03, 0x32, 0x42, 0x82, 0x82, 0x47, 0xAA, 0xFF,
+	}
+	targets, err := decodeAutoPollResp(resp)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(targets) != 2 {
+		t.Errorf("unexpected target number: %d", len(targets))
+		return
+	}
+	if a, ok := targets[0].Target.(*TargetA); !ok || targets[0].Type != PollTypeMifare || a.SAK != 0x08 ||
+		!bytes.Equal(a.UID, []byte{0xDE, 0xAD, 0xBE, 0xEF}) {
+		t.Errorf("unexpected first target: %#v", targets[0])
+	}
+	if f, ok := targets[1].Target.(*TargetFeliCa); !ok || f.Tg() != 0x02 || f.BrTy() != FeliCa212 || f.SystemCode != nil {
+		t.Errorf("unexpected second target: %#v", targets[1])
+	}
+}
+
+func TestDecodeAutoPollDEP(t *testing.T) {
+	resp := []byte{
+		0x01, PollTypeDEPActive424, 0x12,
+		0x01, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0A,
+		0x00, 0x00, 0x00, 0x0E, 0x32, 0x46, 0x66,
+	}
+	targets, err := decodeAutoPollResp(resp)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	d, ok := targets[0].Target.(*TargetDEP)
+	if !ok || !d.Active || d.BrTy() != FeliCa424 || d.PP != 0x32 || !bytes.Equal(d.GeneralBytes, []byte{0x46, 0x66}) {
+		t.Errorf("unexpected DEP target: %#v", targets[0])
+	}
+}
@@ -218,3 +218,115 @@ func TestPn532_MifareClassicWriteBlock(t *testing.T) {
 		log.Fatal("write block failed")
 	}
 }
+
+func TestPn532_RFConfiguration(t *testing.T) {
+	ports, err := serial.GetPortsList()
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+	if len(ports) == 0 {
+		log.Println("no device, skip test")
+		t.SkipNow()
+		return
+	}
+	device, err := QuickInit(ports[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := device.RFMaxRetries(0xFF, 0x01, 0x02); err != nil {
+		log.Fatal(err)
+	}
+	if err := device.RFField(false, false); err != nil {
+		log.Fatal(err)
+	}
+	if err := device.RFField(true, true); err != nil {
+		log.Fatal(err)
+	}
+	log.Print("rf configuration ok")
+}
+
+func TestPn532_InListPassiveTarget(t *testing.T) {
+	ports, err := serial.GetPortsList()
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+	if len(ports) == 0 {
+		log.Println("no device, skip test")
+		t.SkipNow()
+		return
+	}
+	device, err := QuickInit(ports[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	targets, err := device.InListPassiveTarget(0x02, ISO14443A)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, target := range targets {
+		log.Printf("target %d: %#v", target.Tg(), target)
+	}
+}
+
+func TestPn532_CommunicateThru(t *testing.T) {
+	ports, err := serial.GetPortsList()
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+	if len(ports) == 0 {
+		log.Println("no device, skip test")
+		t.SkipNow()
+		return
+	}
+	device, err := QuickInit(ports[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if _, err := device.ReadPassiveTarget(ISO14443A); err != nil {
+		log.Fatal(err)
+	}
+	if err := device.HaltA(); err != nil {
+		log.Fatal(err)
+	}
+	atqa, err := device.TransmitShortFrame(ShortFrameWUPA)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("ATQA: % X", atqa)
+}
+
+func TestPn532_Identify(t *testing.T) {
+	ports, err := serial.GetPortsList()
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+	if len(ports) == 0 {
+		log.Println("no device, skip test")
+		t.SkipNow()
+		return
+	}
+	device, err := QuickInit(ports[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	targets, err := device.InListPassiveTarget(0x01, ISO14443A)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(targets) == 0 {
+		log.Fatal("no target")
+	}
+	info, err := device.Identify(targets[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("card: %s", info)
+}
@@ -64,3 +64,15 @@ const (
 // Either send a command with large preamble containing dummy data
 // Or send first a 0x55 dummy byte and wait for the waking up delay before sending the command frame.
 var WakeUp = []byte{0x55, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+
+// RFConfiguration CfgItem
+const (
+	RFCfgField               byte = 0x01 // RF field
+	RFCfgVariousTimings      byte = 0x02 // Various timings
+	RFCfgMaxRtyCOM           byte = 0x04 // MaxRtyCOM
+	RFCfgMaxRetries          byte = 0x05 // Max retries
+	RFCfgAnalogSettings106A  byte = 0x0A // Analog settings for the baudrate 106 kbps type A
+	RFCfgAnalogSettings212   byte = 0x0B // Analog settings for the baudrate 212/424 kbps
+	RFCfgAnalogSettingsTypeB byte = 0x0C // Analog settings for the type B
+	RFCfgAnalogSettings14443 byte = 0x0D // Analog settings for the baudrate 212/424 and 848 kbps with ISO/IEC14443-4 protocol
+)
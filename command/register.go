@@ -0,0 +1,27 @@
+package command
+
+// PN532 CIU 寄存器地址 用于ReadRegister/WriteRegister
+const (
+	CIUMode        uint16 = 0x6301 // Defines general modes for transmitting and receiving
+	CIUTxMode      uint16 = 0x6302 // Defines the transmission data rate and framing during transmission
+	CIURxMode      uint16 = 0x6303 // Defines the transmission data rate and framing during receiving
+	CIUTxControl   uint16 = 0x6304 // Controls the logical behaviour of the antenna driver pins TX1 and TX2
+	CIUTxAuto      uint16 = 0x6305 // Controls the settings of the antenna driver
+	CIUTxSel       uint16 = 0x6306 // Selects the internal sources for the antenna driver
+	CIURxSel       uint16 = 0x6307 // Selects internal receiver settings
+	CIURxThreshold uint16 = 0x6308 // Selects thresholds for the bit decoder
+	CIUDemod       uint16 = 0x6309 // Defines demodulator settings
+	CIUManualRCV   uint16 = 0x630D // Allows manual fine tuning of the internal receiver
+	CIUStatus2     uint16 = 0x6338 // Contains status flags of the receiver, transmitter and Data Mode Detector
+	CIUBitFraming  uint16 = 0x633D // Adjustments for bit oriented frames
+	CIUColl        uint16 = 0x633E // Defines the first bit collision detected on the RF interface
+)
+
+// CIU 寄存器中的位
+const (
+	CIUTxModeTxCRCEn        byte = 0x80 // CIU_TxMode: enables the CRC generation during data transmission
+	CIURxModeRxCRCEn        byte = 0x80 // CIU_RxMode: enables the CRC calculation during reception
+	CIUManualRCVParityDis   byte = 0x10 // CIU_ManualRCV: disables the parity generation and check
+	CIUStatus2MFCrypto1On   byte = 0x08 // CIU_Status2: indicates that the Mifare Crypto1 unit is switched on
+	CIUBitFramingTxLastBits byte = 0x07 // CIU_BitFraming: number of bits of the last byte that will be transmitted
+)
@@ -0,0 +1,105 @@
+package pn532
+
+import (
+	"errors"
+	"github.com/asjdf/pn532/command"
+)
+
+// 7位短帧
+const (
+	ShortFrameREQA byte = 0x26
+	ShortFrameWUPA byte = 0x52
+)
+
+// InCommunicateThru 将数据原样发送给目标 不做任何协议处理 返回目标的响应
+// CRC与奇偶校验由CIU当前的寄存器设置决定 需要修改时使用 CommunicateThru
+func (p *Pn532) InCommunicateThru(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, errors.New("data must not be empty")
+	}
+	resp, err := p.execute(append([]byte{command.InCommunicateThru}, data...))
+	if err != nil {
+		return nil, err
+	}
+	if err := checkStatusResp(resp); err != nil {
+		return nil, err
+	}
+	return resp[1:], nil
+}
+
+// ThruOptions 原始交换时对CIU的临时设置 零值表示保持默认的CRC与奇偶校验
+type ThruOptions struct {
+	NoTxCRC    bool // 发送时不由硬件附加CRC
+	NoRxCRC    bool // 接收时不由硬件校验及去除CRC
+	NoParity   bool // 关闭奇偶校验的生成与检查
+	TxLastBits byte // 最后一个字节发送的位数 0表示完整的8位
+}
+
+// CommunicateThru 按opt临时修改CIU寄存器后进行原始交换 结束后恢复寄存器原本的值
+func (p *Pn532) CommunicateThru(data []byte, opt *ThruOptions) (resp []byte, err error) {
+	if opt == nil {
+		return p.InCommunicateThru(data)
+	}
+	if opt.TxLastBits > 7 {
+		return nil, errors.New("tx last bits must be between 0 and 7")
+	}
+	regs := []uint16{command.CIUTxMode, command.CIURxMode, command.CIUManualRCV, command.CIUBitFraming}
+	saved, err := p.ReadRegister(regs...)
+	if err != nil {
+		return nil, err
+	}
+	modified := []byte{
+		setBits(saved[0], command.CIUTxModeTxCRCEn, !opt.NoTxCRC),
+		setBits(saved[1], command.CIURxModeRxCRCEn, !opt.NoRxCRC),
+		setBits(saved[2], command.CIUManualRCVParityDis, opt.NoParity),
+		saved[3]&^command.CIUBitFramingTxLastBits | opt.TxLastBits,
+	}
+	if err := p.writeChangedRegisters(regs, saved, modified); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if restoreErr := p.writeChangedRegisters(regs, modified, saved); restoreErr != nil && err == nil {
+			resp, err = nil, restoreErr
+		}
+	}()
+	return p.InCommunicateThru(data)
+}
+
+// writeChangedRegisters 只写入与当前值不同的寄存器
+func (p *Pn532) writeChangedRegisters(addrs []uint16, current, target []byte) error {
+	var regs []RegisterValue
+	for i, addr := range addrs {
+		if current[i] != target[i] {
+			regs = append(regs, RegisterValue{Addr: addr, Value: target[i]})
+		}
+	}
+	return p.WriteRegister(regs...)
+}
+
+func setBits(v, mask byte, on bool) byte {
+	if on {
+		return v | mask
+	}
+	return v &^ mask
+}
+
+// TransmitShortFrame 发送7位短帧(REQA/WUPA) 返回未经CRC处理的响应 通常为ATQA
+func (p *Pn532) TransmitShortFrame(cmd byte) ([]byte, error) {
+	if cmd&0x80 != 0 {
+		return nil, errors.New("short frame must be 7 bits")
+	}
+	return p.CommunicateThru([]byte{cmd}, &ThruOptions{NoTxCRC: true, NoRxCRC: true, TxLastBits: 7})
+}
+
+// HaltA 发送HLTA 使type A卡片进入HALT状态 卡片不会响应 因此超时视为成功
+func (p *Pn532) HaltA() error {
+	_, err := p.CommunicateThru(AppendCRCA([]byte{0x50, 0x00}), &ThruOptions{NoTxCRC: true})
+	var status StatusError
+	if errors.As(err, &status) && status == StatusTimeout {
+		return nil
+	}
+	if err == nil {
+		return errors.New("unexpected response to HLTA")
+	}
+	return err
+}
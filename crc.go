@@ -0,0 +1,57 @@
+package pn532
+
+// crc16 ISO/IEC14443 使用的反射CRC16 多项式 x^16 + x^12 + x^5 + 1
+func crc16(init uint16, data []byte) uint16 {
+	crc := init
+	for _, b := range data {
+		crc ^= uint16(b)
+		for i := 0; i < 8; i++ {
+			if crc&0x0001 != 0 {
+				crc = crc>>1 ^ 0x8408
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc
+}
+
+// CRCA 计算ISO/IEC14443-3 type A的CRC_A 低字节在前
+func CRCA(data []byte) []byte {
+	crc := crc16(0x6363, data)
+	return []byte{byte(crc), byte(crc >> 8)}
+}
+
+// CRCB 计算ISO/IEC14443-3 type B的CRC_B 低字节在前
+func CRCB(data []byte) []byte {
+	crc := ^crc16(0xFFFF, data)
+	return []byte{byte(crc), byte(crc >> 8)}
+}
+
+// AppendCRCA 在数据后附加CRC_A
+func AppendCRCA(data []byte) []byte {
+	return append(append([]byte(nil), data...), CRCA(data)...)
+}
+
+// AppendCRCB 在数据后附加CRC_B
+func AppendCRCB(data []byte) []byte {
+	return append(append([]byte(nil), data...), CRCB(data)...)
+}
+
+// CheckCRCA 校验以CRC_A结尾的数据
+func CheckCRCA(data []byte) bool {
+	if len(data) < 2 {
+		return false
+	}
+	crc := CRCA(data[:len(data)-2])
+	return crc[0] == data[len(data)-2] && crc[1] == data[len(data)-1]
+}
+
+// CheckCRCB 校验以CRC_B结尾的数据
+func CheckCRCB(data []byte) bool {
+	if len(data) < 2 {
+		return false
+	}
+	crc := CRCB(data[:len(data)-2])
+	return crc[0] == data[len(data)-2] && crc[1] == data[len(data)-1]
+}
@@ -0,0 +1,23 @@
+package pn532
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCRC(t *testing.T) {
+	check := []byte("123456789")
+	if crc := CRCA(check); !bytes.Equal(crc, []byte{0x05, 0xBF}) {
+		t.Errorf("unexpected CRC_A: % X", crc)
+	}
+	if crc := CRCB(check); !bytes.Equal(crc, []byte{0x6E, 0x90}) {
+		t.Errorf("unexpected CRC_B: % X", crc)
+	}
+	// HLTA
+	if frame := AppendCRCA([]byte{0x50, 0x00}); !bytes.Equal(frame, []byte{0x50, 0x00, 0x57, 0xCD}) {
+		t.Errorf("unexpected HLTA frame: % X", frame)
+	}
+	if !CheckCRCB(AppendCRCB(check)) || CheckCRCA(AppendCRCB(check)) {
+		t.Error("crc check failed")
+	}
+}
@@ -0,0 +1,612 @@
+package pn532
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DESFire 原生命令
+const (
+	dfCmdGetVersion           byte = 0x60
+	dfCmdAdditionalFrame      byte = 0xAF
+	dfCmdSelectApplication    byte = 0x5A
+	dfCmdCreateApplication    byte = 0xCA
+	dfCmdDeleteApplication    byte = 0xDA
+	dfCmdGetApplicationIDs    byte = 0x6A
+	dfCmdGetFreeMemory        byte = 0x6E
+	dfCmdFormatPICC           byte = 0xFC
+	dfCmdGetFileIDs           byte = 0x6F
+	dfCmdGetFileSettings      byte = 0xF5
+	dfCmdCreateStdDataFile    byte = 0xCD
+	dfCmdCreateBackupDataFile byte = 0xCB
+	dfCmdCreateValueFile      byte = 0xCC
+	dfCmdCreateLinearRecord   byte = 0xC1
+	dfCmdCreateCyclicRecord   byte = 0xC0
+	dfCmdDeleteFile           byte = 0xDF
+	dfCmdReadData             byte = 0xBD
+	dfCmdWriteData            byte = 0x3D
+	dfCmdGetValue             byte = 0x6C
+	dfCmdCredit               byte = 0x0C
+	dfCmdDebit                byte = 0xDC
+	dfCmdLimitedCredit        byte = 0x1C
+	dfCmdWriteRecord          byte = 0x3B
+	dfCmdReadRecords          byte = 0xBB
+	dfCmdClearRecordFile      byte = 0xEB
+	dfCmdCommitTransaction    byte = 0xC7
+	dfCmdAbortTransaction     byte = 0xA7
+)
+
+// desfireFrameData 每一帧命令最多携带的参数与数据 超过时通过AF帧继续发送
+// 原生命令帧最大为60字节 ISO封装还需要APDU头 统一取较小的值
+const desfireFrameData = 52
+
+// DESFireStatus DESFire的状态码 作为错误返回时不会是 DESFireOK
+type DESFireStatus byte
+
+const (
+	DESFireOK                  DESFireStatus = 0x00
+	DESFireNoChanges           DESFireStatus = 0x0C
+	DESFireOutOfEEPROM         DESFireStatus = 0x0E
+	DESFireIllegalCommand      DESFireStatus = 0x1C
+	DESFireIntegrityError      DESFireStatus = 0x1E
+	DESFireNoSuchKey           DESFireStatus = 0x40
+	DESFireLengthError         DESFireStatus = 0x7E
+	DESFirePermissionDenied    DESFireStatus = 0x9D
+	DESFireParameterError      DESFireStatus = 0x9E
+	DESFireApplicationNotFound DESFireStatus = 0xA0
+	DESFireAppIntegrityError   DESFireStatus = 0xA1
+	DESFireAuthenticationError DESFireStatus = 0xAE
+	DESFireAdditionalFrame     DESFireStatus = 0xAF
+	DESFireBoundaryError       DESFireStatus = 0xBE
+	DESFirePICCIntegrityError  DESFireStatus = 0xC1
+	DESFireCommandAborted      DESFireStatus = 0xCA
+	DESFirePICCDisabled        DESFireStatus = 0xCD
+	DESFireCountError          DESFireStatus = 0xCE
+	DESFireDuplicateError      DESFireStatus = 0xDE
+	DESFireEEPROMError         DESFireStatus = 0xEE
+	DESFireFileNotFound        DESFireStatus = 0xF0
+	DESFireFileIntegrityError  DESFireStatus = 0xF1
+)
+
+var desfireStatusMessages = map[DESFireStatus]string{
+	DESFireOK:                  "operation ok",
+	DESFireNoChanges:           "no changes",
+	DESFireOutOfEEPROM:         "insufficient NV memory",
+	DESFireIllegalCommand:      "command code not supported",
+	DESFireIntegrityError:      "CRC or MAC does not match",
+	DESFireNoSuchKey:           "invalid key number",
+	DESFireLengthError:         "length of command string invalid",
+	DESFirePermissionDenied:    "permission denied",
+	DESFireParameterError:      "value of the parameter invalid",
+	DESFireApplicationNotFound: "application not found",
+	DESFireAppIntegrityError:   "application integrity error",
+	DESFireAuthenticationError: "authentication error",
+	DESFireAdditionalFrame:     "additional frame expected",
+	DESFireBoundaryError:       "boundary error",
+	DESFirePICCIntegrityError:  "PICC integrity error",
+	DESFireCommandAborted:      "command aborted",
+	DESFirePICCDisabled:        "PICC disabled",
+	DESFireCountError:          "maximum number of applications or files reached",
+	DESFireDuplicateError:      "application or file already exists",
+	DESFireEEPROMError:         "EEPROM error",
+	DESFireFileNotFound:        "file not found",
+	DESFireFileIntegrityError:  "file integrity error",
+}
+
+func (e DESFireStatus) Error() string {
+	if msg, ok := desfireStatusMessages[e]; ok {
+		return fmt.Sprintf("desfire status %02X: %s", byte(e), msg)
+	}
+	return fmt.Sprintf("desfire status %02X", byte(e))
+}
+
+// DESFireKeyType 应用密钥的类型 与密钥数量一起编码在CreateApplication中
+type DESFireKeyType byte
+
+const (
+	DESFireKeyDES    DESFireKeyType = 0x00 // DES / 2K3DES
+	DESFireKey3K3DES DESFireKeyType = 0x40
+	DESFireKeyAES    DESFireKeyType = 0x80
+)
+
+// DESFireCommMode 文件的通信方式 MAC与加密通信需要先认证
+type DESFireCommMode byte
+
+const (
+	DESFireCommPlain DESFireCommMode = 0x00
+	DESFireCommMAC   DESFireCommMode = 0x01
+	DESFireCommFull  DESFireCommMode = 0x03
+)
+
+// DESFireFileType 文件类型
+type DESFireFileType byte
+
+const (
+	DESFireStdDataFile      DESFireFileType = 0x00
+	DESFireBackupDataFile   DESFireFileType = 0x01
+	DESFireValueFile        DESFireFileType = 0x02
+	DESFireLinearRecordFile DESFireFileType = 0x03
+	DESFireCyclicRecordFile DESFireFileType = 0x04
+)
+
+// 访问权限中的特殊密钥编号
+const (
+	DESFireFreeAccess byte = 0x0E // 不需要认证
+	DESFireNoAccess   byte = 0x0F // 禁止访问
+)
+
+// DESFireAccessRights 文件的访问权限 各字段为需要认证的密钥编号
+type DESFireAccessRights struct {
+	Read      byte
+	Write     byte
+	ReadWrite byte
+	Change    byte // 修改访问权限
+}
+
+// DESFireFreeAccessRights 所有操作都不需要认证
+var DESFireFreeAccessRights = DESFireAccessRights{DESFireFreeAccess, DESFireFreeAccess, DESFireFreeAccess, DESFireFreeAccess}
+
+// bytes 低字节在前 依次为 RW|Change Read|Write 的半字节
+func (a DESFireAccessRights) bytes() []byte {
+	return []byte{a.ReadWrite<<4 | a.Change&0x0F, a.Read<<4 | a.Write&0x0F}
+}
+
+func parseDESFireAccessRights(b []byte) DESFireAccessRights {
+	return DESFireAccessRights{ReadWrite: b[0] >> 4, Change: b[0] & 0x0F, Read: b[1] >> 4, Write: b[1] & 0x0F}
+}
+
+// DESFireFileSettings GetFileSettings的结果 只有与文件类型对应的字段有效
+type DESFireFileSettings struct {
+	Type   DESFireFileType
+	Comm   DESFireCommMode
+	Access DESFireAccessRights
+
+	Size int // 数据文件
+
+	LowerLimit           int32 // 值文件
+	UpperLimit           int32
+	LimitedCreditValue   int32
+	LimitedCreditEnabled bool
+
+	RecordSize int // 记录文件
+	MaxRecords int
+	Records    int
+}
+
+// DESFireVersion GetVersion的结果
+type DESFireVersion struct {
+	Hardware       *VersionInfo
+	Software       *VersionInfo
+	UID            []byte
+	BatchNo        []byte
+	ProductionWeek byte // BCD
+	ProductionYear byte // BCD
+}
+
+// DESFire MIFARE DESFire EV1/EV2/EV3 原生命令 只支持明文通信
+type DESFire struct {
+	// ISOWrap 使用ISO/IEC7816-4封装(CLA 0x90 INS为命令码 状态字为91xx)发送原生命令
+	ISOWrap bool
+
+	tr Transceiver
+}
+
+// NewDESFire 通过tr发送DESFire命令 tr可以是 *Pn532 或者 *IsoDep
+func NewDESFire(tr Transceiver) *DESFire {
+	return &DESFire{tr: tr}
+}
+
+// DESFire 在当前选择的ISO/IEC14443-4目标上使用DESFire命令
+func (p *Pn532) DESFire(t *TargetA) (*DESFire, error) {
+	if t == nil || p.current != Target(t) || t.State() != TargetSelected {
+		return nil, ErrNoTarget
+	}
+	if !t.ISO14443_4() {
+		return nil, errors.New("target is not ISO/IEC14443-4 compliant")
+	}
+	return NewDESFire(p), nil
+}
+
+// frame 发送一帧 返回状态码与数据
+func (d *DESFire) frame(cmd byte, data []byte) (DESFireStatus, []byte, error) {
+	if d.ISOWrap {
+		c := &CommandAPDU{CLA: 0x90, INS: cmd, Data: data, Ne: 256}
+		resp, err := transmitOnce(d.tr, c)
+		if err != nil {
+			return 0, nil, err
+		}
+		if resp.SW1 != 0x91 {
+			return 0, nil, SWError(resp.SW())
+		}
+		return DESFireStatus(resp.SW2), resp.Data, nil
+	}
+	resp, err := d.tr.Transceive(append([]byte{cmd}, data...))
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(resp) < 1 {
+		return 0, nil, errors.New("empty DESFire response")
+	}
+	return DESFireStatus(resp[0]), resp[1:], nil
+}
+
+// command 发送命令并返回完整的响应数据
+// 参数过长时分成多帧 卡片以AF要求后续帧 响应以AF结束时发送AF取回剩余的数据
+func (d *DESFire) command(cmd byte, data []byte) ([]byte, error) {
+	part := data
+	if len(part) > desfireFrameData {
+		part = data[:desfireFrameData]
+	}
+	status, resp, err := d.frame(cmd, part)
+	data = data[len(part):]
+	for err == nil && len(data) > 0 {
+		if status != DESFireAdditionalFrame {
+			break
+		}
+		part = data
+		if len(part) > desfireFrameData {
+			part = data[:desfireFrameData]
+		}
+		status, resp, err = d.frame(dfCmdAdditionalFrame, part)
+		data = data[len(part):]
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > 0 && status == DESFireOK {
+		return nil, errors.New("DESFire command completed before all data was sent")
+	}
+	out := resp
+	for status == DESFireAdditionalFrame {
+		if status, resp, err = d.frame(dfCmdAdditionalFrame, nil); err != nil {
+			return nil, err
+		}
+		out = append(out, resp...)
+	}
+	if status != DESFireOK {
+		return nil, status
+	}
+	return out, nil
+}
+
+// le24 3字节低字节在前的长度或偏移
+func le24(n int) ([]byte, error) {
+	if n < 0 || n > 0xFFFFFF {
+		return nil, errors.New("value must fit in 24 bits")
+	}
+	return []byte{byte(n), byte(n >> 8), byte(n >> 16)}, nil
+}
+
+func parseLE24(b []byte) int {
+	return int(b[0]) | int(b[1])<<8 | int(b[2])<<16
+}
+
+func le32(v int32) []byte {
+	return []byte{byte(v), byte(v >> 8), byte(v >> 16), byte(v >> 24)}
+}
+
+func parseLE32(b []byte) int32 {
+	return int32(uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24)
+}
+
+// GetVersion 读取硬件与软件版本 UID 批次号与生产日期
+func (d *DESFire) GetVersion() (*DESFireVersion, error) {
+	resp, err := d.command(dfCmdGetVersion, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp) < 28 {
+		return nil, errors.New("unexpected GetVersion response")
+	}
+	hw, err := parseVersionInfo(resp[:7])
+	if err != nil {
+		return nil, err
+	}
+	sw, err := parseVersionInfo(resp[7:14])
+	if err != nil {
+		return nil, err
+	}
+	return &DESFireVersion{
+		Hardware:       hw,
+		Software:       sw,
+		UID:            append([]byte(nil), resp[14:21]...),
+		BatchNo:        append([]byte(nil), resp[21:26]...),
+		ProductionWeek: resp[26],
+		ProductionYear: resp[27],
+	}, nil
+}
+
+// aidBytes 检查应用编号并按低字节在前编码
+func aidBytes(aid uint32) ([]byte, error) {
+	if aid > 0xFFFFFF {
+		return nil, errors.New("application ID must fit in 24 bits")
+	}
+	return DESFireAID(aid), nil
+}
+
+// SelectApplication 选择应用 0表示PICC层
+func (d *DESFire) SelectApplication(aid uint32) error {
+	data, err := aidBytes(aid)
+	if err != nil {
+		return err
+	}
+	_, err = d.command(dfCmdSelectApplication, data)
+	return err
+}
+
+// CreateApplication 在PICC层创建应用 keySettings为应用主密钥设置 numKeys为1-14
+func (d *DESFire) CreateApplication(aid uint32, keySettings byte, numKeys byte, keyType DESFireKeyType) error {
+	data, err := aidBytes(aid)
+	if err != nil {
+		return err
+	}
+	if aid == 0 || numKeys < 1 || numKeys > 14 {
+		return errors.New("invalid application parameters")
+	}
+	_, err = d.command(dfCmdCreateApplication, append(data, keySettings, numKeys|byte(keyType)))
+	return err
+}
+
+// DeleteApplication 删除应用
+func (d *DESFire) DeleteApplication(aid uint32) error {
+	data, err := aidBytes(aid)
+	if err != nil {
+		return err
+	}
+	_, err = d.command(dfCmdDeleteApplication, data)
+	return err
+}
+
+// GetApplicationIDs 列出PICC上的所有应用
+func (d *DESFire) GetApplicationIDs() ([]uint32, error) {
+	resp, err := d.command(dfCmdGetApplicationIDs, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp)%3 != 0 {
+		return nil, errors.New("unexpected GetApplicationIDs response")
+	}
+	aids := make([]uint32, 0, len(resp)/3)
+	for i := 0; i < len(resp); i += 3 {
+		aids = append(aids, uint32(parseLE24(resp[i:])))
+	}
+	return aids, nil
+}
+
+// GetFreeMemory PICC剩余的存储空间
+func (d *DESFire) GetFreeMemory() (int, error) {
+	resp, err := d.command(dfCmdGetFreeMemory, nil)
+	if err != nil {
+		return 0, err
+	}
+	if len(resp) != 3 {
+		return 0, errors.New("unexpected GetFreeMemory response")
+	}
+	return parseLE24(resp), nil
+}
+
+// FormatPICC 删除所有应用与文件 需要先选择PICC层并以PICC主密钥认证
+func (d *DESFire) FormatPICC() error {
+	_, err := d.command(dfCmdFormatPICC, nil)
+	return err
+}
+
+// GetFileIDs 列出当前应用中的所有文件
+func (d *DESFire) GetFileIDs() ([]byte, error) {
+	return d.command(dfCmdGetFileIDs, nil)
+}
+
+// GetFileSettings 读取文件的类型 通信方式 访问权限与大小
+func (d *DESFire) GetFileSettings(fileNo byte) (*DESFireFileSettings, error) {
+	resp, err := d.command(dfCmdGetFileSettings, []byte{fileNo})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp) < 4 {
+		return nil, errors.New("unexpected GetFileSettings response")
+	}
+	s := &DESFireFileSettings{
+		Type:   DESFireFileType(resp[0]),
+		Comm:   DESFireCommMode(resp[1]),
+		Access: parseDESFireAccessRights(resp[2:4]),
+	}
+	rest := resp[4:]
+	switch s.Type {
+	case DESFireStdDataFile, DESFireBackupDataFile:
+		if len(rest) < 3 {
+			return nil, errors.New("unexpected GetFileSettings response")
+		}
+		s.Size = parseLE24(rest)
+	case DESFireValueFile:
+		if len(rest) < 13 {
+			return nil, errors.New("unexpected GetFileSettings response")
+		}
+		s.LowerLimit = parseLE32(rest)
+		s.UpperLimit = parseLE32(rest[4:])
+		s.LimitedCreditValue = parseLE32(rest[8:])
+		s.LimitedCreditEnabled = rest[12]&0x01 != 0
+	case DESFireLinearRecordFile, DESFireCyclicRecordFile:
+		if len(rest) < 9 {
+			return nil, errors.New("unexpected GetFileSettings response")
+		}
+		s.RecordSize = parseLE24(rest)
+		s.MaxRecords = parseLE24(rest[3:])
+		s.Records = parseLE24(rest[6:])
+	}
+	return s, nil
+}
+
+// fileHeader 文件编号 通信方式与访问权限
+func fileHeader(fileNo byte, comm DESFireCommMode, access DESFireAccessRights) []byte {
+	return append([]byte{fileNo, byte(comm)}, access.bytes()...)
+}
+
+func (d *DESFire) createDataFile(cmd, fileNo byte, comm DESFireCommMode, access DESFireAccessRights, size int) error {
+	s, err := le24(size)
+	if err != nil {
+		return err
+	}
+	_, err = d.command(cmd, append(fileHeader(fileNo, comm, access), s...))
+	return err
+}
+
+// CreateStdDataFile 创建标准数据文件 写入立即生效
+func (d *DESFire) CreateStdDataFile(fileNo byte, comm DESFireCommMode, access DESFireAccessRights, size int) error {
+	return d.createDataFile(dfCmdCreateStdDataFile, fileNo, comm, access, size)
+}
+
+// CreateBackupDataFile 创建备份数据文件 写入需要 CommitTransaction 才会生效
+func (d *DESFire) CreateBackupDataFile(fileNo byte, comm DESFireCommMode, access DESFireAccessRights, size int) error {
+	return d.createDataFile(dfCmdCreateBackupDataFile, fileNo, comm, access, size)
+}
+
+// CreateValueFile 创建值文件 value为初始值 limitedCredit为true时允许 LimitedCredit
+func (d *DESFire) CreateValueFile(fileNo byte, comm DESFireCommMode, access DESFireAccessRights, lower, upper, value int32, limitedCredit bool) error {
+	if lower > upper || value < lower || value > upper {
+		return errors.New("value out of limits")
+	}
+	data := fileHeader(fileNo, comm, access)
+	data = append(data, le32(lower)...)
+	data = append(data, le32(upper)...)
+	data = append(data, le32(value)...)
+	enabled := byte(0x00)
+	if limitedCredit {
+		enabled = 0x01
+	}
+	_, err := d.command(dfCmdCreateValueFile, append(data, enabled))
+	return err
+}
+
+func (d *DESFire) createRecordFile(cmd, fileNo byte, comm DESFireCommMode, access DESFireAccessRights, recordSize, maxRecords int) error {
+	size, err := le24(recordSize)
+	if err != nil {
+		return err
+	}
+	records, err := le24(maxRecords)
+	if err != nil {
+		return err
+	}
+	data := append(fileHeader(fileNo, comm, access), size...)
+	_, err = d.command(cmd, append(data, records...))
+	return err
+}
+
+// CreateLinearRecordFile 创建线性记录文件 记录写满后无法再追加
+func (d *DESFire) CreateLinearRecordFile(fileNo byte, comm DESFireCommMode, access DESFireAccessRights, recordSize, maxRecords int) error {
+	return d.createRecordFile(dfCmdCreateLinearRecord, fileNo, comm, access, recordSize, maxRecords)
+}
+
+// CreateCyclicRecordFile 创建循环记录文件 写满后覆盖最旧的记录 实际可用的记录数为maxRecords-1
+func (d *DESFire) CreateCyclicRecordFile(fileNo byte, comm DESFireCommMode, access DESFireAccessRights, recordSize, maxRecords int) error {
+	return d.createRecordFile(dfCmdCreateCyclicRecord, fileNo, comm, access, recordSize, maxRecords)
+}
+
+// DeleteFile 删除当前应用中的文件
+func (d *DESFire) DeleteFile(fileNo byte) error {
+	_, err := d.command(dfCmdDeleteFile, []byte{fileNo})
+	return err
+}
+
+// rangeParams 文件编号 偏移与长度
+func rangeParams(fileNo byte, offset, length int) ([]byte, error) {
+	o, err := le24(offset)
+	if err != nil {
+		return nil, err
+	}
+	l, err := le24(length)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte{fileNo}, o...), l...), nil
+}
+
+// ReadData 从数据文件读取 length为0时读到文件末尾
+func (d *DESFire) ReadData(fileNo byte, offset, length int) ([]byte, error) {
+	params, err := rangeParams(fileNo, offset, length)
+	if err != nil {
+		return nil, err
+	}
+	return d.command(dfCmdReadData, params)
+}
+
+// WriteData 写入数据文件 数据过长时自动分帧发送
+func (d *DESFire) WriteData(fileNo byte, offset int, data []byte) error {
+	params, err := rangeParams(fileNo, offset, len(data))
+	if err != nil {
+		return err
+	}
+	_, err = d.command(dfCmdWriteData, append(params, data...))
+	return err
+}
+
+// WriteRecord 在记录文件中追加一条记录或者写入当前记录的offset处 需要 CommitTransaction 才会生效
+func (d *DESFire) WriteRecord(fileNo byte, offset int, data []byte) error {
+	params, err := rangeParams(fileNo, offset, len(data))
+	if err != nil {
+		return err
+	}
+	_, err = d.command(dfCmdWriteRecord, append(params, data...))
+	return err
+}
+
+// ReadRecords 从最新的第offset条记录开始向旧读取count条 count为0时读取全部
+func (d *DESFire) ReadRecords(fileNo byte, offset, count int) ([]byte, error) {
+	params, err := rangeParams(fileNo, offset, count)
+	if err != nil {
+		return nil, err
+	}
+	return d.command(dfCmdReadRecords, params)
+}
+
+// ClearRecordFile 清空记录文件 需要 CommitTransaction 才会生效
+func (d *DESFire) ClearRecordFile(fileNo byte) error {
+	_, err := d.command(dfCmdClearRecordFile, []byte{fileNo})
+	return err
+}
+
+// GetValue 读取值文件
+func (d *DESFire) GetValue(fileNo byte) (int32, error) {
+	resp, err := d.command(dfCmdGetValue, []byte{fileNo})
+	if err != nil {
+		return 0, err
+	}
+	if len(resp) != 4 {
+		return 0, errors.New("unexpected GetValue response")
+	}
+	return parseLE32(resp), nil
+}
+
+func (d *DESFire) valueCommand(cmd, fileNo byte, value int32) error {
+	if value < 0 {
+		return errors.New("value must not be negative")
+	}
+	_, err := d.command(cmd, append([]byte{fileNo}, le32(value)...))
+	return err
+}
+
+// Credit 增加值文件 需要 CommitTransaction 才会生效
+func (d *DESFire) Credit(fileNo byte, value int32) error {
+	return d.valueCommand(dfCmdCredit, fileNo, value)
+}
+
+// Debit 减少值文件 需要 CommitTransaction 才会生效
+func (d *DESFire) Debit(fileNo byte, value int32) error {
+	return d.valueCommand(dfCmdDebit, fileNo, value)
+}
+
+// LimitedCredit 只需要写权限的有限增加 最多为上次提交的事务中Debit的总额
+func (d *DESFire) LimitedCredit(fileNo byte, value int32) error {
+	return d.valueCommand(dfCmdLimitedCredit, fileNo, value)
+}
+
+// CommitTransaction 提交当前应用中备份文件 值文件与记录文件的所有修改
+func (d *DESFire) CommitTransaction() error {
+	_, err := d.command(dfCmdCommitTransaction, nil)
+	return err
+}
+
+// AbortTransaction 放弃当前应用中未提交的修改
+func (d *DESFire) AbortTransaction() error {
+	_, err := d.command(dfCmdAbortTransaction, nil)
+	return err
+}
@@ -0,0 +1,290 @@
+package pn532
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"testing"
+)
+
+type fakeDESFireFile struct {
+	settings  []byte // GetFileSettings中文件类型之后的部分
+	data      []byte
+	value     int32
+	committed int32
+}
+
+// fakeDESFire 模拟DESFire的部分原生命令 每帧最多返回59字节 输入超过一帧时需要AF帧
+type fakeDESFire struct {
+	apps     map[uint32]map[byte]*fakeDESFireFile
+	selected uint32
+	pending  []byte // 等待后续帧的命令
+	need     int    // pending完整时的长度
+	out      []byte // 等待AF取回的数据
+	frames   int
+}
+
+func newFakeDESFire() *fakeDESFire {
+	return &fakeDESFire{apps: map[uint32]map[byte]*fakeDESFireFile{0: {}}}
+}
+
+func (f *fakeDESFire) Transceive(cmd []byte) ([]byte, error) {
+	f.frames++
+	if cmd[0] == dfCmdAdditionalFrame && f.pending != nil {
+		f.pending = append(f.pending, cmd[1:]...)
+		if len(f.pending) < f.need {
+			return []byte{byte(DESFireAdditionalFrame)}, nil
+		}
+		cmd, f.pending = f.pending, nil
+	} else if cmd[0] == dfCmdAdditionalFrame {
+		return f.respond(f.out, DESFireOK)
+	} else if cmd[0] == dfCmdWriteData || cmd[0] == dfCmdWriteRecord {
+		f.need = 8 + parseLE24(cmd[5:])
+		if len(cmd) < f.need {
+			f.pending = append([]byte(nil), cmd...)
+			return []byte{byte(DESFireAdditionalFrame)}, nil
+		}
+	}
+	data, status := f.execute(cmd[0], cmd[1:])
+	return f.respond(data, status)
+}
+
+// respond 数据超过59字节时以AF分帧返回
+func (f *fakeDESFire) respond(data []byte, status DESFireStatus) ([]byte, error) {
+	if len(data) > 59 {
+		f.out = data[59:]
+		return append([]byte{byte(DESFireAdditionalFrame)}, data[:59]...), nil
+	}
+	f.out = nil
+	return append([]byte{byte(status)}, data...), nil
+}
+
+func (f *fakeDESFire) execute(cmd byte, p []byte) ([]byte, DESFireStatus) {
+	files := f.apps[f.selected]
+	var file *fakeDESFireFile
+	if len(p) > 0 {
+		file = files[p[0]]
+	}
+	switch cmd {
+	case dfCmdGetVersion:
+		return mustHex("0401013300180504010133001805" + "04112233445566" + "BA5E000000" + "20" + "21"), DESFireOK
+	case dfCmdSelectApplication:
+		aid := uint32(parseLE24(p))
+		if _, ok := f.apps[aid]; !ok {
+			return nil, DESFireApplicationNotFound
+		}
+		f.selected = aid
+	case dfCmdCreateApplication:
+		aid := uint32(parseLE24(p))
+		if _, ok := f.apps[aid]; ok {
+			return nil, DESFireDuplicateError
+		}
+		f.apps[aid] = map[byte]*fakeDESFireFile{}
+	case dfCmdDeleteApplication:
+		delete(f.apps, uint32(parseLE24(p)))
+	case dfCmdGetApplicationIDs:
+		var aids []int
+		for aid := range f.apps {
+			if aid != 0 {
+				aids = append(aids, int(aid))
+			}
+		}
+		sort.Ints(aids)
+		var out []byte
+		for _, aid := range aids {
+			out = append(out, DESFireAID(uint32(aid))...)
+		}
+		return out, DESFireOK
+	case dfCmdCreateStdDataFile:
+		files[p[0]] = &fakeDESFireFile{settings: p[1:], data: make([]byte, parseLE24(p[4:]))}
+	case dfCmdCreateValueFile:
+		v := parseLE32(p[12:])
+		files[p[0]] = &fakeDESFireFile{settings: append([]byte{byte(DESFireValueFile)}, p[1:]...), value: v, committed: v}
+	case dfCmdGetFileSettings:
+		if file == nil {
+			return nil, DESFireFileNotFound
+		}
+		if file.data != nil {
+			return append([]byte{byte(DESFireStdDataFile)}, file.settings...), DESFireOK
+		}
+		return file.settings, DESFireOK
+	case dfCmdReadData, dfCmdWriteData:
+		if file == nil {
+			return nil, DESFireFileNotFound
+		}
+		offset, length := parseLE24(p[1:]), parseLE24(p[4:])
+		if cmd == dfCmdReadData && length == 0 {
+			length = len(file.data) - offset
+		}
+		if offset+length > len(file.data) {
+			return nil, DESFireBoundaryError
+		}
+		if cmd == dfCmdReadData {
+			return append([]byte(nil), file.data[offset:offset+length]...), DESFireOK
+		}
+		copy(file.data[offset:], p[7:])
+	case dfCmdGetValue:
+		return le32(file.committed), DESFireOK
+	case dfCmdCredit:
+		file.value += parseLE32(p[1:])
+	case dfCmdDebit:
+		if file.value-parseLE32(p[1:]) < parseLE32(file.settings[4:]) {
+			return nil, DESFireBoundaryError
+		}
+		file.value -= parseLE32(p[1:])
+	case dfCmdCommitTransaction, dfCmdAbortTransaction:
+		for _, file := range files {
+			if cmd == dfCmdCommitTransaction {
+				file.committed = file.value
+			} else {
+				file.value = file.committed
+			}
+		}
+	default:
+		return nil, DESFireIllegalCommand
+	}
+	return nil, DESFireOK
+}
+
+// isoWrapped 把ISO封装的APDU转换为原生命令
+type isoWrapped struct {
+	*fakeDESFire
+}
+
+func (w isoWrapped) Transceive(apdu []byte) ([]byte, error) {
+	if apdu[0] != 0x90 || apdu[2] != 0x00 || apdu[3] != 0x00 {
+		return []byte{0x6E, 0x00}, nil
+	}
+	cmd := []byte{apdu[1]}
+	if len(apdu) > 5 {
+		cmd = append(cmd, apdu[5:5+int(apdu[4])]...)
+	}
+	resp, err := w.fakeDESFire.Transceive(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return append(resp[1:], 0x91, resp[0]), nil
+}
+
+func TestDESFire(t *testing.T) {
+	for _, wrap := range []bool{false, true} {
+		card := newFakeDESFire()
+		d := NewDESFire(card)
+		if wrap {
+			d = NewDESFire(isoWrapped{card})
+			d.ISOWrap = true
+		}
+
+		v, err := d.GetVersion()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.Hardware.MajorVersion != 0x33 || !bytes.Equal(v.UID, mustHex("04112233445566")) || v.ProductionYear != 0x21 {
+			t.Errorf("unexpected version: %+v", v)
+		}
+
+		for aid := uint32(0x100001); aid <= 0x100016; aid++ {
+			if err := d.CreateApplication(aid, 0x0F, 2, DESFireKeyAES); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := d.CreateApplication(0x100001, 0x0F, 2, DESFireKeyAES); err != DESFireDuplicateError {
+			t.Errorf("unexpected error: %v", err)
+		}
+		// 22个应用需要两帧
+		aids, err := d.GetApplicationIDs()
+		if err != nil || len(aids) != 22 || aids[21] != 0x100016 {
+			t.Errorf("unexpected applications: %X %v", aids, err)
+		}
+		if err := d.DeleteApplication(0x100016); err != nil {
+			t.Fatal(err)
+		}
+		if err := d.SelectApplication(0x100016); err != DESFireApplicationNotFound {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if err := d.SelectApplication(0x100001); err != nil {
+			t.Fatal(err)
+		}
+
+		access := DESFireAccessRights{Read: 1, Write: 2, ReadWrite: 3, Change: 0}
+		if err := d.CreateStdDataFile(1, DESFireCommPlain, access, 200); err != nil {
+			t.Fatal(err)
+		}
+		s, err := d.GetFileSettings(1)
+		if err != nil || s.Type != DESFireStdDataFile || s.Access != access || s.Size != 200 {
+			t.Errorf("unexpected settings: %+v %v", s, err)
+		}
+		data := make([]byte, 150)
+		for i := range data {
+			data[i] = byte(i)
+		}
+		card.frames = 0
+		if err := d.WriteData(1, 10, data); err != nil {
+			t.Fatal(err)
+		}
+		// 7字节参数与150字节数据分4帧发送
+		if card.frames != 4 {
+			t.Errorf("write sent in %d frames", card.frames)
+		}
+		read, err := d.ReadData(1, 10, 150)
+		if err != nil || !bytes.Equal(read, data) {
+			t.Errorf("unexpected data: % X %v", read, err)
+		}
+		if read, err := d.ReadData(1, 0, 0); err != nil || len(read) != 200 {
+			t.Errorf("unexpected data: %d %v", len(read), err)
+		}
+		if _, err := d.ReadData(1, 100, 101); err != DESFireBoundaryError {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if _, err := d.ReadData(9, 0, 0); !errors.Is(err, DESFireFileNotFound) {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		if err := d.CreateValueFile(2, DESFireCommPlain, DESFireFreeAccessRights, 0, 1000, 100, false); err != nil {
+			t.Fatal(err)
+		}
+		if s, err := d.GetFileSettings(2); err != nil || s.Type != DESFireValueFile || s.UpperLimit != 1000 {
+			t.Errorf("unexpected settings: %+v %v", s, err)
+		}
+		if err := d.Credit(2, 50); err != nil {
+			t.Fatal(err)
+		}
+		if v, _ := d.GetValue(2); v != 100 {
+			t.Errorf("value changed before commit: %d", v)
+		}
+		if err := d.CommitTransaction(); err != nil {
+			t.Fatal(err)
+		}
+		if err := d.Debit(2, 200); err != DESFireBoundaryError {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if err := d.Debit(2, 30); err != nil {
+			t.Fatal(err)
+		}
+		if err := d.AbortTransaction(); err != nil {
+			t.Fatal(err)
+		}
+		if v, err := d.GetValue(2); err != nil || v != 150 {
+			t.Errorf("unexpected value: %d %v", v, err)
+		}
+		if err := d.Credit(2, -1); err == nil {
+			t.Error("negative credit accepted")
+		}
+	}
+}
+
+func TestDESFireEncoding(t *testing.T) {
+	access := DESFireAccessRights{Read: 0x1, Write: 0x2, ReadWrite: 0x3, Change: 0x4}
+	if b := access.bytes(); !bytes.Equal(b, []byte{0x34, 0x12}) || parseDESFireAccessRights(b) != access {
+		t.Errorf("unexpected access rights: % X", b)
+	}
+	if v := parseLE32(le32(-5)); v != -5 {
+		t.Errorf("unexpected value: %d", v)
+	}
+	if _, err := le24(0x1000000); err == nil {
+		t.Error("25-bit value accepted")
+	}
+	if err := NewDESFire(newFakeDESFire()).SelectApplication(0x1000000); err == nil {
+		t.Error("invalid AID accepted")
+	}
+}
@@ -0,0 +1,184 @@
+package pn532
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"errors"
+)
+
+// CMAC 按照NIST SP 800-38B(RFC 4493)计算c的CMAC 分组长度为8或16字节
+func CMAC(c cipher.Block, msg []byte) []byte {
+	bs := c.BlockSize()
+	padTo := (len(msg) + bs - 1) / bs * bs
+	if padTo == 0 {
+		padTo = bs
+	}
+	return cmacPadded(c, msg, padTo)
+}
+
+// cmacSubkeys 由E(0)推导K1与K2
+func cmacSubkeys(c cipher.Block) ([]byte, []byte) {
+	bs := c.BlockSize()
+	rb := byte(0x87)
+	if bs == 8 {
+		rb = 0x1B
+	}
+	shift := func(in []byte) []byte {
+		out := make([]byte, len(in))
+		for i := range in {
+			out[i] = in[i] << 1
+			if i+1 < len(in) {
+				out[i] |= in[i+1] >> 7
+			}
+		}
+		if in[0]&0x80 != 0 {
+			out[len(out)-1] ^= rb
+		}
+		return out
+	}
+	l := make([]byte, bs)
+	c.Encrypt(l, l)
+	k1 := shift(l)
+	return k1, shift(k1)
+}
+
+// cmacPadded 将消息填充到padTo字节后计算CMAC
+// 消息长度等于padTo时最后一块与K1异或 否则以80 00..填充后与K2异或
+// AN10922要求把较短的输入填充到两个分组 因此填充长度不一定是下一个分组边界
+func cmacPadded(c cipher.Block, msg []byte, padTo int) []byte {
+	bs := c.BlockSize()
+	k1, k2 := cmacSubkeys(c)
+	data := append([]byte(nil), msg...)
+	subkey := k1
+	if len(data) < padTo {
+		data = append(data, 0x80)
+		data = append(data, make([]byte, padTo-len(data))...)
+		subkey = k2
+	}
+	last := data[len(data)-bs:]
+	for i := range last {
+		last[i] ^= subkey[i]
+	}
+	mac := make([]byte, bs)
+	for i := 0; i < len(data); i += bs {
+		for j := 0; j < bs; j++ {
+			mac[j] ^= data[i+j]
+		}
+		c.Encrypt(mac, mac)
+	}
+	return mac
+}
+
+// newTDESCipher 支持8字节DES 16字节2K3DES与24字节3K3DES密钥
+func newTDESCipher(key []byte) (cipher.Block, error) {
+	switch len(key) {
+	case 8:
+		return des.NewCipher(key)
+	case 16:
+		return des.NewTripleDESCipher(append(append([]byte(nil), key...), key[:8]...))
+	case 24:
+		return des.NewTripleDESCipher(key)
+	}
+	return nil, errors.New("DES key length must be 8, 16 or 24")
+}
+
+// DiversifyAES128 AN10922 AES-128密钥分散 CMAC(K, 01 || M) M为1-31字节
+func DiversifyAES128(master, m []byte) ([]byte, error) {
+	if len(m) < 1 || len(m) > 31 {
+		return nil, errors.New("diversification input must be 1-31 bytes")
+	}
+	if len(master) != 16 {
+		return nil, errors.New("master key must be 16 bytes")
+	}
+	c, err := aes.NewCipher(master)
+	if err != nil {
+		return nil, err
+	}
+	return cmacPadded(c, append([]byte{0x01}, m...), 32), nil
+}
+
+// diversifyTDES 依次以各个常量计算CMAC并拼接 前8字节的最低位换回主密钥的密钥版本
+func diversifyTDES(master, m []byte, constants ...byte) ([]byte, error) {
+	if len(m) < 1 || len(m) > 15 {
+		return nil, errors.New("diversification input must be 1-15 bytes")
+	}
+	c, err := newTDESCipher(master)
+	if err != nil {
+		return nil, err
+	}
+	var key []byte
+	for _, constant := range constants {
+		key = append(key, cmacPadded(c, append([]byte{constant}, m...), 16)...)
+	}
+	for i := 0; i < 8; i++ {
+		key[i] = key[i]&^0x01 | master[i]&0x01
+	}
+	return key, nil
+}
+
+// Diversify2K3DES AN10922 2K3DES密钥分散 CMAC(K, 21 || M) || CMAC(K, 22 || M) M为1-15字节
+func Diversify2K3DES(master, m []byte) ([]byte, error) {
+	if len(master) != 16 {
+		return nil, errors.New("master key must be 16 bytes")
+	}
+	return diversifyTDES(master, m, 0x21, 0x22)
+}
+
+// Diversify3K3DES AN10922 3K3DES密钥分散 以31 32 33为常量计算三次CMAC M为1-15字节
+func Diversify3K3DES(master, m []byte) ([]byte, error) {
+	if len(master) != 24 {
+		return nil, errors.New("master key must be 24 bytes")
+	}
+	return diversifyTDES(master, m, 0x31, 0x32, 0x33)
+}
+
+// DiversifyClassicKey MIFARE Classic 48位密钥分散 AN10922没有定义Classic的分散方式
+// 这是本库自己的方案: 按 DiversifyAES128 分散后取前6个字节 与其他实现不一定兼容
+func DiversifyClassicKey(master, m []byte) ([]byte, error) {
+	key, err := DiversifyAES128(master, m)
+	if err != nil {
+		return nil, err
+	}
+	return key[:6], nil
+}
+
+// DESFireAID 将3字节的DESFire应用编号按低字节在前编码
+func DESFireAID(aid uint32) []byte {
+	return []byte{byte(aid), byte(aid >> 8), byte(aid >> 16)}
+}
+
+// DivInput 构造分散输入 UID || AID || 系统标识 aid与systemID可以为nil
+func DivInput(uid, aid, systemID []byte) []byte {
+	m := append([]byte(nil), uid...)
+	m = append(m, aid...)
+	return append(m, systemID...)
+}
+
+// ClassicDivInput 构造 DiversifyClassicKey 使用的分散输入 格式同样由本库定义
+// 认证UID(4字节) || 扇区号 || 密码类型(0x60/0x61) || 系统标识
+func ClassicDivInput(uid []byte, sector int, keyType byte, systemID []byte) ([]byte, error) {
+	authUID, err := ClassicAuthUID(uid)
+	if err != nil {
+		return nil, err
+	}
+	if sector < 0 || sector >= Classic4K.Sectors {
+		return nil, errors.New("sector out of range")
+	}
+	m := append([]byte(nil), authUID...)
+	m = append(m, byte(sector), keyType)
+	return append(m, systemID...), nil
+}
+
+// MifareClassicAuthenticateDiversified 使用由主密钥与目标UID分散得到的密码认证扇区
+func (p *Pn532) MifareClassicAuthenticateDiversified(t *TargetA, g ClassicGeometry, sector int, keyType byte, master, systemID []byte) error {
+	m, err := ClassicDivInput(t.UID, sector, keyType, systemID)
+	if err != nil {
+		return err
+	}
+	key, err := DiversifyClassicKey(master, m)
+	if err != nil {
+		return err
+	}
+	return p.MifareClassicAuthenticateSector(t, g, sector, keyType, key)
+}
@@ -0,0 +1,90 @@
+package pn532
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"testing"
+)
+
+func mustHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestCMAC(t *testing.T) {
+	// RFC 4493
+	c, _ := aes.NewCipher(mustHex("2b7e151628aed2a6abf7158809cf4f3c"))
+	for _, tc := range []struct{ msg, mac string }{
+		{"", "bb1d6929e95937287fa37d129b756746"},
+		{"6bc1bee22e409f96e93d7e117393172a", "070a16b46b4d4144f79bdd9dd04a287c"},
+		{"6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411", "dfa66747de9ae63030ca32611497c827"},
+	} {
+		if mac := CMAC(c, mustHex(tc.msg)); !bytes.Equal(mac, mustHex(tc.mac)) {
+			t.Errorf("CMAC(%s) = %X", tc.msg, mac)
+		}
+	}
+}
+
+func TestDiversifyAES128(t *testing.T) {
+	// AN10922 2.2.1
+	m := DivInput(mustHex("04782E21801D80"), DESFireAID(0xF54230), []byte("NXP Abu"))
+	if !bytes.Equal(m, mustHex("04782E21801D803042F54E585020416275")) {
+		t.Fatalf("unexpected input: %X", m)
+	}
+	key, err := DiversifyAES128(mustHex("00112233445566778899AABBCCDDEEFF"), m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(key, mustHex("A8DD63A3B89D54B37CA802473FDA9175")) {
+		t.Errorf("unexpected key: %X", key)
+	}
+	if _, err := DiversifyAES128(mustHex("00112233445566778899AABBCCDDEEFF"), make([]byte, 32)); err == nil {
+		t.Error("long input accepted")
+	}
+}
+
+func TestDiversifyTDES(t *testing.T) {
+	// AN10922 2K3DES与3K3DES的例子 分散后的密钥保留主密钥的版本0x55
+	master := mustHex("00112233445566778899AABBCCDDEEFF")
+	key, err := Diversify2K3DES(master, mustHex("04782E21801D803042F54E58502041"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(key, mustHex("16F9587D9E8910C96B9648D006107DD7")) {
+		t.Errorf("unexpected key: %X", key)
+	}
+	key3, err := Diversify3K3DES(mustHex("00112233445566778899AABBCCDDEEFF0102030405060708"), mustHex("04782E21801D803042F54E5850"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(key3, mustHex("2E0DD03774D3FA9B5705AB0BDA91CA0B55B8E07FCDBF10EC")) {
+		t.Errorf("unexpected key: %X", key3)
+	}
+	if _, err := Diversify2K3DES(master, make([]byte, 16)); err == nil {
+		t.Error("long input accepted")
+	}
+}
+
+func TestClassicDivInput(t *testing.T) {
+	m, err := ClassicDivInput(mustHex("04112233445566"), 3, 0x60, []byte{0xAB})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(m, mustHex("3344556603"+"60"+"AB")) {
+		t.Errorf("unexpected input: %X", m)
+	}
+	// 本库自己的方案 固定输出以免无意中改变已分散的密钥
+	key, err := DiversifyClassicKey(mustHex("00112233445566778899AABBCCDDEEFF"), m)
+	if err != nil || !bytes.Equal(key, mustHex("D0F5C8D086A3")) {
+		t.Errorf("unexpected key: %X %v", key, err)
+	}
+	// 与AN10922 AES-128例子的前6个字节一致
+	key, err = DiversifyClassicKey(mustHex("00112233445566778899AABBCCDDEEFF"), mustHex("04782E21801D803042F54E585020416275"))
+	if err != nil || !bytes.Equal(key, mustHex("A8DD63A3B89D")) {
+		t.Errorf("unexpected key: %X %v", key, err)
+	}
+}
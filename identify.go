@@ -0,0 +1,318 @@
+package pn532
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Product 卡片型号
+type Product int
+
+const (
+	ProductUnknown Product = iota
+	ProductMifareClassicMini
+	ProductMifareClassic1K
+	ProductMifareClassic2K
+	ProductMifareClassic4K
+	ProductMifareUltralight
+	ProductMifareUltralightEV1
+	ProductMifareUltralightC
+	ProductNTAG213
+	ProductNTAG215
+	ProductNTAG216
+	ProductMifareDESFire
+	ProductMifareDESFireEV1
+	ProductMifareDESFireEV2
+	ProductMifareDESFireEV3
+	ProductMifarePlus
+	ProductSmartMX // SmartMX / JCOP
+	ProductFeliCa
+	ProductNTAGI2C // NTAG I2C / NTAG I2C plus
+)
+
+var productNames = map[Product]string{
+	ProductUnknown:             "Unknown",
+	ProductMifareClassicMini:   "MIFARE Classic Mini",
+	ProductMifareClassic1K:     "MIFARE Classic 1K",
+	ProductMifareClassic2K:     "MIFARE Classic 2K",
+	ProductMifareClassic4K:     "MIFARE Classic 4K",
+	ProductMifareUltralight:    "MIFARE Ultralight",
+	ProductMifareUltralightEV1: "MIFARE Ultralight EV1",
+	ProductMifareUltralightC:   "MIFARE Ultralight C",
+	ProductNTAG213:             "NTAG213",
+	ProductNTAG215:             "NTAG215",
+	ProductNTAG216:             "NTAG216",
+	ProductMifareDESFire:       "MIFARE DESFire",
+	ProductMifareDESFireEV1:    "MIFARE DESFire EV1",
+	ProductMifareDESFireEV2:    "MIFARE DESFire EV2",
+	ProductMifareDESFireEV3:    "MIFARE DESFire EV3",
+	ProductMifarePlus:          "MIFARE Plus",
+	ProductSmartMX:             "SmartMX / JCOP",
+	ProductFeliCa:              "FeliCa",
+	ProductNTAGI2C:             "NTAG I2C",
+}
+
+func (p Product) String() string {
+	if name, ok := productNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("Product(%d)", int(p))
+}
+
+// Capability 卡片支持的功能
+type Capability uint
+
+const (
+	CapCrypto1              Capability = 1 << iota // MIFARE Classic Crypto1 认证
+	CapISO14443_4                                  // ISO/IEC14443-4 协议
+	CapNFCType2                                    // NFC Forum Type 2 Tag
+	CapOriginalitySignature                        // READ_SIG 原厂签名
+	CapCounter                                     // 单调计数器
+	CapPassword                                    // 32位密码保护
+	Cap3DESAuth                                    // 3DES 认证
+	CapAESAuth                                     // AES 认证
+)
+
+var capabilityNames = []string{
+	"Crypto1", "ISO14443-4", "NFC Type 2", "originality signature", "counter", "password", "3DES", "AES",
+}
+
+func (c Capability) String() string {
+	var names []string
+	for i, name := range capabilityNames {
+		if c&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// VersionInfo GET_VERSION 的响应 NTAG/Ultralight EV1 与 DESFire 的硬件信息格式相同
+type VersionInfo struct {
+	VendorID       byte // 0x04 为NXP
+	ProductType    byte
+	ProductSubtype byte
+	MajorVersion   byte
+	MinorVersion   byte
+	StorageSize    byte // 高7位n表示容量为2^n字节 最低位为1时表示容量介于2^n与2^(n+1)之间
+	ProtocolType   byte
+}
+
+func parseVersionInfo(data []byte) (*VersionInfo, error) {
+	if len(data) != 7 {
+		return nil, errors.New("invalid version length")
+	}
+	return &VersionInfo{
+		VendorID:       data[0],
+		ProductType:    data[1],
+		ProductSubtype: data[2],
+		MajorVersion:   data[3],
+		MinorVersion:   data[4],
+		StorageSize:    data[5],
+		ProtocolType:   data[6],
+	}, nil
+}
+
+// CardInfo 卡片识别结果
+type CardInfo struct {
+	Product      Product
+	MemorySize   int // 用户可用的存储空间(字节) 未知时为0
+	Capabilities Capability
+	Version      *VersionInfo // GET_VERSION 的结果 仅NTAG/Ultralight EV1/DESFire存在
+}
+
+// Has 卡片是否具有某一功能
+func (c *CardInfo) Has(capability Capability) bool {
+	return c.Capabilities&capability == capability
+}
+
+func (c *CardInfo) String() string {
+	if c.MemorySize == 0 {
+		return fmt.Sprintf("%s [%s]", c.Product, c.Capabilities)
+	}
+	return fmt.Sprintf("%s, %d bytes [%s]", c.Product, c.MemorySize, c.Capabilities)
+}
+
+// 需要进一步探测的卡片
+const (
+	probeNone = iota
+	probeUltralight
+	probeDESFire
+)
+
+// identifyATQASAK 按照NXP AN10833根据ATQA/SAK以及ATS历史字节进行初步判断
+func identifyATQASAK(t *TargetA) (*CardInfo, int) {
+	info := &CardInfo{}
+	if t.ISO14443_4() {
+		info.Capabilities |= CapISO14443_4
+	}
+	switch t.SAK {
+	case 0x00:
+		info.Product = ProductMifareUltralight
+		info.MemorySize = 48
+		info.Capabilities |= CapNFCType2
+		return info, probeUltralight
+	case 0x09:
+		info.Product, info.MemorySize = ProductMifareClassicMini, 320
+	case 0x08, 0x88:
+		info.Product, info.MemorySize = ProductMifareClassic1K, 1024
+	case 0x19:
+		info.Product, info.MemorySize = ProductMifareClassic2K, 2048
+	case 0x18:
+		info.Product, info.MemorySize = ProductMifareClassic4K, 4096
+	case 0x10:
+		info.Product, info.MemorySize = ProductMifarePlus, 2048
+	case 0x11:
+		info.Product, info.MemorySize = ProductMifarePlus, 4096
+	case 0x28:
+		// SmartMX 模拟的 MIFARE Classic 1K
+		info.Product, info.MemorySize = ProductSmartMX, 1024
+	case 0x38:
+		info.Product, info.MemorySize = ProductSmartMX, 4096
+	case 0x20:
+		switch {
+		case t.ATQA == [2]byte{0x03, 0x44} || t.ATQA == [2]byte{0x03, 0x04}:
+			info.Product = ProductMifareDESFire
+			info.Capabilities |= Cap3DESAuth
+			return info, probeDESFire
+		case t.ATS != nil && bytes.HasPrefix(t.ATS.Historical, []byte{0xC1, 0x05, 0x2F, 0x2F}):
+			info.Product = ProductMifarePlus
+			info.Capabilities |= CapAESAuth
+		case t.ATS != nil && bytes.Contains(t.ATS.Historical, []byte("JCOP")):
+			info.Product = ProductSmartMX
+		}
+		return info, probeNone
+	}
+	if t.SAK&0x08 != 0 {
+		info.Capabilities |= CapCrypto1
+	}
+	if info.Product == ProductMifarePlus && t.SAK&0x08 == 0 {
+		info.Capabilities |= CapAESAuth // SL2
+	}
+	return info, probeNone
+}
+
+// identifyUltralightVersion 根据GET_VERSION区分NTAG21x与Ultralight EV1
+func identifyUltralightVersion(info *CardInfo, v *VersionInfo) {
+	info.Version = v
+	if v.VendorID != 0x04 {
+		return
+	}
+	switch v.ProductType {
+	case 0x03: // MIFARE Ultralight
+		info.Product = ProductMifareUltralightEV1
+		info.Capabilities |= CapOriginalitySignature | CapCounter | CapPassword
+		switch v.StorageSize {
+		case 0x0B:
+			info.MemorySize = 48
+		case 0x0E:
+			info.MemorySize = 128
+		}
+	case 0x04: // NTAG
+		if v.ProductSubtype == 0x05 { // NTAG I2C 容量编码与NTAG216相同 需要根据子类型区分
+			info.Product, info.MemorySize = ProductNTAGI2C, 888
+			if v.StorageSize == 0x15 {
+				info.MemorySize = 1904
+			}
+			info.Capabilities |= CapOriginalitySignature
+			if v.MajorVersion == 0x02 { // NTAG I2C plus
+				info.Capabilities |= CapPassword
+			}
+			return
+		}
+		info.Capabilities |= CapOriginalitySignature | CapCounter | CapPassword
+		switch v.StorageSize {
+		case 0x0F:
+			info.Product, info.MemorySize = ProductNTAG213, 144
+		case 0x11:
+			info.Product, info.MemorySize = ProductNTAG215, 504
+		case 0x13:
+			info.Product, info.MemorySize = ProductNTAG216, 888
+		}
+	}
+}
+
+// identifyDESFireVersion 根据GetVersion的硬件信息区分DESFire的版本
+func identifyDESFireVersion(info *CardInfo, v *VersionInfo) {
+	info.Version = v
+	info.MemorySize = 1 << (v.StorageSize >> 1)
+	switch v.MajorVersion {
+	case 0x00:
+		info.Product = ProductMifareDESFire
+	case 0x01:
+		info.Product = ProductMifareDESFireEV1
+		info.Capabilities |= CapAESAuth
+	case 0x12:
+		info.Product = ProductMifareDESFireEV2
+		info.Capabilities |= CapAESAuth | CapOriginalitySignature
+	case 0x33:
+		info.Product = ProductMifareDESFireEV3
+		info.Capabilities |= CapAESAuth | CapOriginalitySignature
+	}
+}
+
+// Identify 识别卡片型号 type A目标会根据需要发送GET_VERSION等命令进一步探测
+// 探测失败的卡片会回到IDLE状态 此时会自动重新激活目标
+func (p *Pn532) Identify(t Target) (*CardInfo, error) {
+	switch target := t.(type) {
+	case *TargetFeliCa:
+		return &CardInfo{Product: ProductFeliCa}, nil
+	case *TargetA:
+		info, probe := identifyATQASAK(target)
+		switch probe {
+		case probeUltralight:
+			return info, p.probeUltralight(target, info)
+		case probeDESFire:
+			return info, p.probeDESFire(info)
+		}
+		return info, nil
+	default:
+		return &CardInfo{}, nil
+	}
+}
+
+func (p *Pn532) probeUltralight(t *TargetA, info *CardInfo) error {
+	if resp, err := p.InCommunicateThru([]byte{0x60}); err == nil && len(resp) == 8 {
+		v, err := parseVersionInfo(resp[1:])
+		if err != nil {
+			return err
+		}
+		identifyUltralightVersion(info, v)
+		return nil
+	}
+	// 不支持GET_VERSION 卡片已回到IDLE状态
+	if err := p.Reactivate(t); err != nil {
+		return err
+	}
+	if resp, err := p.InCommunicateThru([]byte{0x1A, 0x00}); err == nil && len(resp) == 9 && resp[0] == 0xAF {
+		info.Product = ProductMifareUltralightC
+		info.MemorySize = 144
+		info.Capabilities |= Cap3DESAuth
+	}
+	// 中止认证或者无响应后同样需要重新激活
+	return p.Reactivate(t)
+}
+
+func (p *Pn532) probeDESFire(info *CardInfo) error {
+	resp, err := p.InDataExchange([]byte{0x60})
+	if err != nil {
+		return err
+	}
+	if len(resp) != 8 || resp[0] != 0xAF {
+		return errors.New("unexpected DESFire GetVersion response")
+	}
+	v, err := parseVersionInfo(resp[1:])
+	if err != nil {
+		return err
+	}
+	identifyDESFireVersion(info, v)
+	// 读完剩下的两帧 结束GetVersion
+	for i := 0; i < 2; i++ {
+		if _, err := p.InDataExchange([]byte{0xAF}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
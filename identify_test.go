@@ -0,0 +1,64 @@
+package pn532
+
+import "testing"
+
+func TestIdentifyATQASAK(t *testing.T) {
+	cases := []struct {
+		atqa    [2]byte
+		sak     byte
+		product Product
+		probe   int
+	}{
+		{[2]byte{0x00, 0x04}, 0x08, ProductMifareClassic1K, probeNone},
+		{[2]byte{0x00, 0x02}, 0x18, ProductMifareClassic4K, probeNone},
+		{[2]byte{0x00, 0x04}, 0x09, ProductMifareClassicMini, probeNone},
+		{[2]byte{0x00, 0x44}, 0x00, ProductMifareUltralight, probeUltralight},
+		{[2]byte{0x03, 0x44}, 0x20, ProductMifareDESFire, probeDESFire},
+	}
+	for _, c := range cases {
+		info, probe := identifyATQASAK(&TargetA{ATQA: c.atqa, SAK: c.sak})
+		if info.Product != c.product || probe != c.probe {
+			t.Errorf("ATQA % X SAK %#X: unexpected result %s %d", c.atqa, c.sak, info, probe)
+		}
+	}
+	info, _ := identifyATQASAK(&TargetA{ATQA: [2]byte{0x00, 0x04}, SAK: 0x08})
+	if !info.Has(CapCrypto1) || info.Has(CapISO14443_4) || info.MemorySize != 1024 {
+		t.Errorf("unexpected capabilities: %s", info)
+	}
+
+	ats, _ := ParseATS([]byte{0x0C, 0x75, 0x77, 0x81, 0x02, 0xC1, 0x05, 0x2F, 0x2F, 0x01, 0xBC, 0xD6})
+	info, _ = identifyATQASAK(&TargetA{ATQA: [2]byte{0x00, 0x44}, SAK: 0x20, ATS: ats})
+	if info.Product != ProductMifarePlus || !info.Has(CapISO14443_4|CapAESAuth) {
+		t.Errorf("unexpected MIFARE Plus result: %s", info)
+	}
+}
+
+func TestIdentifyVersion(t *testing.T) {
+	// NTAG215 GET_VERSION: 00 04 04 02 01 00 11 03
+	v, err := parseVersionInfo([]byte{0x04, 0x04, 0x02, 0x01, 0x00, 0x11, 0x03})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	info := &CardInfo{}
+	identifyUltralightVersion(info, v)
+	if info.Product != ProductNTAG215 || info.MemorySize != 504 || !info.Has(CapOriginalitySignature) {
+		t.Errorf("unexpected NTAG result: %s", info)
+	}
+
+	// NTAG I2C plus 2k: 00 04 04 05 02 02 15 03
+	v, _ = parseVersionInfo([]byte{0x04, 0x04, 0x05, 0x02, 0x02, 0x15, 0x03})
+	info = &CardInfo{}
+	identifyUltralightVersion(info, v)
+	if info.Product != ProductNTAGI2C || info.MemorySize != 1904 || !info.Has(CapPassword) {
+		t.Errorf("unexpected NTAG I2C result: %s", info)
+	}
+
+	// DESFire EV2 4K: 04 01 01 12 00 18 05
+	v, _ = parseVersionInfo([]byte{0x04, 0x01, 0x01, 0x12, 0x00, 0x18, 0x05})
+	info = &CardInfo{}
+	identifyDESFireVersion(info, v)
+	if info.Product != ProductMifareDESFireEV2 || info.MemorySize != 4096 {
+		t.Errorf("unexpected DESFire result: %s", info)
+	}
+}
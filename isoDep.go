@@ -0,0 +1,373 @@
+package pn532
+
+import (
+	"errors"
+	"fmt"
+	"github.com/asjdf/pn532/command"
+	"time"
+)
+
+// 块类型
+const (
+	pcbIBlock    byte = 0x02
+	pcbRBlock    byte = 0xA2
+	pcbChaining  byte = 0x10 // I-block: 链式传输
+	pcbNAK       byte = 0x10 // R-block: NAK
+	pcbCID       byte = 0x08
+	pcbNAD       byte = 0x04
+	pcbBlockNum  byte = 0x01
+	pcbSDeselect byte = 0xC2
+	pcbSWTX      byte = 0xF2
+)
+
+// fwtUnit 256 * 16 / fc
+const fwtUnit = 302 * time.Microsecond
+
+// IsoDepMaxFSDI PN532普通帧最多只能携带约250字节 因此FSD最大取128
+const IsoDepMaxFSDI byte = 0x07
+
+// maxThruData 普通帧LEN最大为255 去掉TFI与命令码后InCommunicateThru最多可携带253字节
+const maxThruData = 253
+
+// ErrIsoDepProtocol 卡片的响应不符合ISO/IEC14443-4
+var ErrIsoDepProtocol = errors.New("iso-dep protocol error")
+
+// IsoDep 在InCommunicateThru之上由软件实现的ISO/IEC14443-4半双工块传输协议
+// 仅在通过 SetParameters 关闭AutoRATS时使用 否则PN532固件会自行处理
+type IsoDep struct {
+	ATS        *ATS
+	MaxRetries int // 传输错误时的最大重试次数
+
+	p        *Pn532
+	fsc      int
+	fsd      int
+	cid      byte
+	useCID   bool
+	fwt      time.Duration
+	wait     time.Duration // 当前块允许卡片使用的响应时间 WTX时为延长后的值
+	blockNum byte
+
+	transceive func([]byte) ([]byte, error)
+	setTimeout func(time.Duration) error
+}
+
+// ActivateIsoDep 向当前选择的type A目标发送RATS并激活ISO/IEC14443-4协议
+// fsdi 为读卡器可接收的帧长度系数 最大为 IsoDepMaxFSDI cid为0x00-0x0E的卡片逻辑编号
+func (p *Pn532) ActivateIsoDep(t *TargetA, fsdi, cid byte) (*IsoDep, error) {
+	if !p.noAutoRATS {
+		return nil, errors.New("AutoRATS is enabled, disable it with SetParameters first")
+	}
+	if t == nil || p.current != Target(t) || t.State() != TargetSelected {
+		return nil, ErrNoTarget
+	}
+	if !t.ISO14443_4() {
+		return nil, errors.New("target is not ISO/IEC14443-4 compliant")
+	}
+	if fsdi > IsoDepMaxFSDI {
+		return nil, fmt.Errorf("fsdi must not be greater than %#X", IsoDepMaxFSDI)
+	}
+	if cid > 0x0E {
+		return nil, errors.New("cid must be between 0x00 and 0x0E")
+	}
+	if err := p.enableCRC(); err != nil {
+		return nil, err
+	}
+
+	d := &IsoDep{
+		MaxRetries: 2,
+		p:          p,
+		fsd:        frameSize(fsdi),
+		cid:        cid,
+		transceive: p.InCommunicateThru,
+		setTimeout: func(fwt time.Duration) error {
+			return p.RFVariousTimings(0x0B, RFTimeoutCode(fwt))
+		},
+	}
+	// RATS的响应时间为默认的FWT
+	if err := d.setTimeout(fwtUnit << 4); err != nil {
+		return nil, err
+	}
+	raw, err := d.transceive([]byte{0xE0, fsdi<<4 | cid})
+	if err != nil {
+		return nil, err
+	}
+	if err := d.init(raw); err != nil {
+		return nil, err
+	}
+	t.RawATS = raw
+	t.ATS = d.ATS
+	time.Sleep(d.ATS.SFGT())
+	return d, nil
+}
+
+// enableCRC ISO/IEC14443-4 的帧由CIU计算CRC
+func (p *Pn532) enableCRC() error {
+	regs := []uint16{command.CIUTxMode, command.CIURxMode}
+	current, err := p.ReadRegister(regs...)
+	if err != nil {
+		return err
+	}
+	return p.writeChangedRegisters(regs, current, []byte{
+		current[0] | command.CIUTxModeTxCRCEn,
+		current[1] | command.CIURxModeRxCRCEn,
+	})
+}
+
+// init 根据ATS初始化协议参数
+func (d *IsoDep) init(raw []byte) error {
+	ats, err := ParseATS(raw)
+	if err != nil {
+		return err
+	}
+	d.ATS = ats
+	d.fsc = ats.FSC()
+	d.useCID = d.cid != 0 && ats.CIDSupported()
+	d.fwt = ats.FWT()
+	d.blockNum = 0
+	return d.setTimeout(d.fwt)
+}
+
+// PPS 修改通信速率 dsi为卡片到读卡器的速率系数 dri为读卡器到卡片的速率系数 0-3分别对应106/212/424/848 kbps
+func (d *IsoDep) PPS(dsi, dri byte) error {
+	if dsi > 0x03 || dri > 0x03 {
+		return errors.New("dsi and dri must be between 0 and 3")
+	}
+	if ta := d.ATS.TA; ta != nil {
+		if dsi > 0 && *ta&(0x08<<dsi) == 0 {
+			return errors.New("PICC to PCD bit rate not supported by the card")
+		}
+		if dri > 0 && *ta&(0x01<<(dri-1)) == 0 {
+			return errors.New("PCD to PICC bit rate not supported by the card")
+		}
+		if *ta&0x80 != 0 && dsi != dri {
+			return errors.New("card requires the same bit rate in both directions")
+		}
+	} else if dsi != 0 || dri != 0 {
+		return errors.New("card only supports 106 kbps")
+	}
+	resp, err := d.transceive([]byte{0xD0 | d.cid, 0x11, dsi<<2 | dri})
+	if err != nil {
+		return err
+	}
+	if len(resp) != 1 || resp[0] != 0xD0|d.cid {
+		return ErrIsoDepProtocol
+	}
+	regs := []uint16{command.CIUTxMode, command.CIURxMode}
+	current, err := d.p.ReadRegister(regs...)
+	if err != nil {
+		return err
+	}
+	return d.p.writeChangedRegisters(regs, current, []byte{
+		current[0]&^0x70 | dri<<4,
+		current[1]&^0x70 | dsi<<4,
+	})
+}
+
+// FSD 读卡器可接收的最大帧长度
+func (d *IsoDep) FSD() int {
+	return d.fsd
+}
+
+// FSC 卡片可接收的最大帧长度
+func (d *IsoDep) FSC() int {
+	return d.fsc
+}
+
+func (d *IsoDep) header(pcb byte) []byte {
+	if d.useCID {
+		return []byte{pcb | pcbCID, d.cid}
+	}
+	return []byte{pcb}
+}
+
+func (d *IsoDep) iBlock(inf []byte, chaining bool) []byte {
+	pcb := pcbIBlock | d.blockNum
+	if chaining {
+		pcb |= pcbChaining
+	}
+	return append(d.header(pcb), inf...)
+}
+
+func (d *IsoDep) rBlock(nak bool) []byte {
+	pcb := pcbRBlock | d.blockNum
+	if nak {
+		pcb |= pcbNAK
+	}
+	return d.header(pcb)
+}
+
+// inf 返回块中PCB/CID/NAD之后的数据
+func (d *IsoDep) inf(block []byte) ([]byte, error) {
+	n := 1
+	if block[0]&pcbCID != 0 {
+		n++
+	}
+	if block[0]&0xC0 == 0x00 && block[0]&pcbNAD != 0 {
+		n++
+	}
+	if len(block) < n {
+		return nil, ErrIsoDepProtocol
+	}
+	return block[n:], nil
+}
+
+func isIBlock(pcb byte) bool {
+	return pcb&0xE2 == 0x02
+}
+
+func isRBlock(pcb byte) bool {
+	return pcb&0xE6 == 0xA2
+}
+
+func isSBlock(pcb byte) bool {
+	return pcb&0xC7 == 0xC2
+}
+
+// isTransmissionError 超时或者帧错误 可以通过重传恢复
+func isTransmissionError(err error) bool {
+	var status StatusError
+	if !errors.As(err, &status) {
+		return false
+	}
+	switch status {
+	case StatusTimeout, StatusCRC, StatusParity, StatusFraming, StatusRFProtocol:
+		return true
+	}
+	return false
+}
+
+// exchange 发送一个块并返回卡片的响应 处理S(WTX)
+func (d *IsoDep) exchange(block []byte) ([]byte, error) {
+	extended := false
+	defer func() {
+		if extended {
+			_ = d.setTimeout(d.fwt)
+		}
+	}()
+	d.wait = d.fwt
+	for {
+		resp, err := d.transceive(block)
+		if err != nil {
+			return nil, err
+		}
+		if len(resp) == 0 {
+			return nil, ErrIsoDepProtocol
+		}
+		if resp[0]&^pcbCID != pcbSWTX {
+			return resp, nil
+		}
+		inf, err := d.inf(resp)
+		if err != nil || len(inf) != 1 {
+			return nil, ErrIsoDepProtocol
+		}
+		wtxm := inf[0] & 0x3F
+		if wtxm == 0 || wtxm > 59 {
+			return nil, ErrIsoDepProtocol
+		}
+		d.wait = d.fwt * time.Duration(wtxm)
+		if err := d.setTimeout(d.wait); err != nil {
+			return nil, err
+		}
+		extended = true
+		block = append(d.header(pcbSWTX), wtxm)
+	}
+}
+
+// isTimeout 卡片在超时时间内没有响应
+func isTimeout(err error) bool {
+	var status StatusError
+	return errors.As(err, &status) && status == StatusTimeout
+}
+
+// exchangeBlock 发送块 出现传输错误时按照协议规则重传
+// piccChaining 为true时表示正在接收卡片的链式数据 此时出错应重发R(ACK)而不是R(NAK)
+// PN532的超时最长为 RFTimeoutMax(约3.28s) 而FWI=14时FWT约为4.95s WTX还会进一步延长
+// 超出上限的部分通过再次发送R-block等待补足 卡片处理完成后会重发最后一个块 这些等待不计入重试次数
+func (d *IsoDep) exchangeBlock(block []byte, piccChaining bool) ([]byte, error) {
+	req := block
+	var waited time.Duration
+	for retry := 0; ; retry++ {
+		resp, err := d.exchange(req)
+		if err != nil {
+			if timeout := RFTimeoutDuration(RFTimeoutCode(d.wait)); isTimeout(err) && waited+timeout < d.wait {
+				waited += timeout
+				retry--
+			} else {
+				waited = 0
+			}
+			if !isTransmissionError(err) || retry >= d.MaxRetries {
+				return nil, err
+			}
+			if piccChaining {
+				req = d.rBlock(false)
+			} else {
+				req = d.rBlock(true)
+			}
+			continue
+		}
+		// 卡片没有收到我们的I-block 需要重传
+		if isIBlock(block[0]) && isRBlock(resp[0]) && resp[0]&pcbNAK == 0 && resp[0]&pcbBlockNum != d.blockNum {
+			if retry >= d.MaxRetries {
+				return nil, ErrIsoDepProtocol
+			}
+			req = block
+			continue
+		}
+		return resp, nil
+	}
+}
+
+// Transceive 发送数据并返回卡片的响应 超过FSC时自动进行链式传输
+func (d *IsoDep) Transceive(data []byte) ([]byte, error) {
+	frame := d.fsc - 2 // CRC由CIU附加
+	if frame > maxThruData {
+		frame = maxThruData
+	}
+	maxInf := frame - len(d.header(0))
+	for len(data) > maxInf {
+		resp, err := d.exchangeBlock(d.iBlock(data[:maxInf], true), false)
+		if err != nil {
+			return nil, err
+		}
+		if !isRBlock(resp[0]) || resp[0]&pcbNAK != 0 || resp[0]&pcbBlockNum != d.blockNum {
+			return nil, ErrIsoDepProtocol
+		}
+		d.blockNum ^= pcbBlockNum
+		data = data[maxInf:]
+	}
+
+	resp, err := d.exchangeBlock(d.iBlock(data, false), false)
+	if err != nil {
+		return nil, err
+	}
+	var out []byte
+	for {
+		if !isIBlock(resp[0]) || resp[0]&pcbBlockNum != d.blockNum {
+			return nil, ErrIsoDepProtocol
+		}
+		d.blockNum ^= pcbBlockNum
+		inf, err := d.inf(resp)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, inf...)
+		if resp[0]&pcbChaining == 0 {
+			return out, nil
+		}
+		if resp, err = d.exchangeBlock(d.rBlock(false), true); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Deselect 发送S(DESELECT) 使卡片进入HALT状态
+func (d *IsoDep) Deselect() error {
+	resp, err := d.transceive(d.header(pcbSDeselect))
+	if err != nil {
+		return err
+	}
+	if len(resp) == 0 || !isSBlock(resp[0]) || resp[0]&^pcbCID != pcbSDeselect {
+		return ErrIsoDepProtocol
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package pn532
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// fakePICC 模拟一张FSC为16字节的ISO/IEC14443-4卡片 将收到的数据原样返回
+type fakePICC struct {
+	t        *testing.T
+	blockNum byte
+	received []byte
+	pending  []byte
+	wtx      bool // 在第一次收到完整数据后先发送S(WTX)
+	drop     bool // 丢弃下一次收到的块 模拟超时
+	late     bool // 处理下一个块的时间超过PN532的超时 响应只能通过R(NAK)取回
+	last     []byte
+	timeouts []time.Duration
+}
+
+func (c *fakePICC) transceive(block []byte) ([]byte, error) {
+	resp, err := c.handle(block)
+	if resp != nil {
+		c.last = resp
+	}
+	if c.late && err == nil {
+		c.late = false
+		return nil, StatusTimeout
+	}
+	return resp, err
+}
+
+func (c *fakePICC) handle(block []byte) ([]byte, error) {
+	if c.drop {
+		c.drop = false
+		return nil, StatusTimeout
+	}
+	pcb := block[0]
+	switch {
+	case isIBlock(pcb):
+		if pcb&pcbBlockNum != c.blockNum {
+			// 重传的块 已经处理过
+			return []byte{pcbRBlock | c.blockNum ^ pcbBlockNum}, nil
+		}
+		c.received = append(c.received, block[1:]...)
+		if pcb&pcbChaining != 0 {
+			ack := []byte{pcbRBlock | c.blockNum}
+			c.blockNum ^= pcbBlockNum
+			return ack, nil
+		}
+		c.pending = c.received
+		c.received = nil
+		if c.wtx {
+			c.wtx = false
+			return []byte{0xF2, 0x02}, nil
+		}
+		return c.next(), nil
+	case pcb&^pcbCID == pcbSWTX:
+		return c.next(), nil
+	case isRBlock(pcb):
+		if pcb&pcbNAK != 0 {
+			if pcb&pcbBlockNum == c.blockNum {
+				// 没有收到读卡器的I-block
+				return []byte{pcbRBlock | c.blockNum ^ pcbBlockNum}, nil
+			}
+			return c.last, nil
+		}
+		c.blockNum ^= pcbBlockNum
+		return c.next(), nil
+	}
+	c.t.Fatalf("unexpected block: % X", block)
+	return nil, nil
+}
+
+// next 以最多13字节的INF回送数据
+func (c *fakePICC) next() []byte {
+	n := len(c.pending)
+	pcb := pcbIBlock | c.blockNum
+	if n > 13 {
+		n = 13
+		pcb |= pcbChaining
+	}
+	resp := append([]byte{pcb}, c.pending[:n]...)
+	c.pending = c.pending[n:]
+	if pcb&pcbChaining == 0 {
+		c.blockNum ^= pcbBlockNum
+	}
+	return resp
+}
+
+func newTestIsoDep(c *fakePICC) *IsoDep {
+	d := &IsoDep{
+		MaxRetries: 2,
+		fsd:        frameSize(IsoDepMaxFSDI),
+		transceive: c.transceive,
+		setTimeout: func(d time.Duration) error {
+			c.timeouts = append(c.timeouts, d)
+			return nil
+		},
+	}
+	if err := d.init([]byte{0x05, 0x70, 0x80, 0x40, 0x00}); err != nil {
+		c.t.Fatal(err)
+	}
+	return d
+}
+
+func TestIsoDepChaining(t *testing.T) {
+	c := &fakePICC{t: t, wtx: true}
+	d := newTestIsoDep(c)
+	if d.FSC() != 16 {
+		t.Errorf("unexpected FSC: %d", d.FSC())
+	}
+	data := make([]byte, 40)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	resp, err := d.Transceive(data)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !bytes.Equal(resp, data) {
+		t.Errorf("unexpected response: % X", resp)
+	}
+	// init WTX 恢复
+	if len(c.timeouts) != 3 || c.timeouts[1] != 2*c.timeouts[0] || c.timeouts[2] != c.timeouts[0] {
+		t.Errorf("unexpected timeouts: %v", c.timeouts)
+	}
+
+	// 第二次交换 块号需要延续
+	resp, err = d.Transceive([]byte{0x01, 0x02})
+	if err != nil || !bytes.Equal(resp, []byte{0x01, 0x02}) {
+		t.Errorf("unexpected second response: % X %v", resp, err)
+	}
+}
+
+func TestIsoDepRetransmit(t *testing.T) {
+	c := &fakePICC{t: t}
+	d := newTestIsoDep(c)
+	c.drop = true
+	// 第一次超时后发送R(NAK) 卡片回应R(ACK)表示没有收到 需要重传I-block
+	resp, err := d.Transceive([]byte{0xAA, 0xBB})
+	if err != nil || !bytes.Equal(resp, []byte{0xAA, 0xBB}) {
+		t.Errorf("unexpected response: % X %v", resp, err)
+	}
+}
+
+func TestIsoDepLongFWT(t *testing.T) {
+	c := &fakePICC{t: t}
+	d := newTestIsoDep(c)
+	d.MaxRetries = 0
+	c.late = true
+	if _, err := d.Transceive([]byte{0xAA}); err != StatusTimeout {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// FWI=14 FWT约为4.95s 超出PN532的超时上限 第一次超时只是等待的一部分
+	c = &fakePICC{t: t}
+	d = newTestIsoDep(c)
+	d.MaxRetries = 0
+	if err := d.init([]byte{0x05, 0x70, 0x80, 0xE0, 0x00}); err != nil {
+		t.Fatal(err)
+	}
+	if d.fwt <= RFTimeoutDuration(RFTimeoutMax) {
+		t.Fatalf("unexpected FWT: %v", d.fwt)
+	}
+	c.late = true
+	resp, err := d.Transceive([]byte{0xAA, 0xBB})
+	if err != nil || !bytes.Equal(resp, []byte{0xAA, 0xBB}) {
+		t.Errorf("unexpected response: % X %v", resp, err)
+	}
+}
@@ -0,0 +1,310 @@
+package pn532
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/asjdf/pn532/command"
+	"hash"
+	"os"
+	"sync"
+)
+
+// ErrKeyNotFound 没有匹配的密钥
+var ErrKeyNotFound = errors.New("key not found")
+
+// KeyRole 密钥的用途
+type KeyRole string
+
+const (
+	KeyRoleA        KeyRole = "A"        // MIFARE Classic 密码A
+	KeyRoleB        KeyRole = "B"        // MIFARE Classic 密码B
+	KeyRolePassword KeyRole = "password" // NTAG/Ultralight EV1 PWD
+	KeyRoleMaster   KeyRole = "master"   // 卡片主密钥 例如DESFire PICC主密钥
+)
+
+// KeyRoleNumber 按编号区分的密钥 例如DESFire应用中的密钥0-13
+func KeyRoleNumber(n int) KeyRole {
+	return KeyRole(fmt.Sprintf("key%d", n))
+}
+
+// AnySlot 匹配所有扇区或应用
+const AnySlot = -1
+
+// KeyRequest 查询密钥的条件
+type KeyRequest struct {
+	Product Product
+	UID     []byte
+	Slot    int // MIFARE Classic 扇区号或DESFire应用编号
+	Role    KeyRole
+}
+
+// KeyProvider 根据卡片与用途提供密钥 找不到时返回 ErrKeyNotFound
+type KeyProvider interface {
+	Key(req KeyRequest) ([]byte, error)
+}
+
+// KeyEntry 密钥条目 Product为ProductUnknown UID为空 Slot为AnySlot时分别匹配所有值
+// 从JSON读取时缺少slot视为AnySlot 而不是扇区0
+type KeyEntry struct {
+	Product Product `json:"product"`
+	UID     []byte  `json:"uid,omitempty"`
+	Slot    int     `json:"slot"`
+	Role    KeyRole `json:"role"`
+	Key     []byte  `json:"key"`
+}
+
+// UnmarshalJSON 缺少slot时默认为AnySlot
+func (e *KeyEntry) UnmarshalJSON(data []byte) error {
+	type entry KeyEntry // 避免递归调用UnmarshalJSON
+	v := entry{Slot: AnySlot}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*e = KeyEntry(v)
+	return nil
+}
+
+// match 条目是否匹配请求 返回值越大越具体 不匹配时返回-1
+func (e *KeyEntry) match(req KeyRequest) int {
+	if e.Role != req.Role {
+		return -1
+	}
+	score := 0
+	if e.Product != ProductUnknown {
+		if e.Product != req.Product {
+			return -1
+		}
+		score++
+	}
+	if e.Slot != AnySlot {
+		if e.Slot != req.Slot {
+			return -1
+		}
+		score += 2
+	}
+	if len(e.UID) > 0 {
+		if !bytes.Equal(e.UID, req.UID) {
+			return -1
+		}
+		score += 4
+	}
+	return score
+}
+
+// MemoryKeyProvider 保存在内存中的密钥 多个条目匹配时使用最具体的条目
+type MemoryKeyProvider struct {
+	mu      sync.RWMutex
+	entries []KeyEntry
+}
+
+// NewMemoryKeyProvider 创建内存密钥源
+func NewMemoryKeyProvider(entries ...KeyEntry) *MemoryKeyProvider {
+	m := &MemoryKeyProvider{}
+	for _, e := range entries {
+		m.Add(e)
+	}
+	return m
+}
+
+// Add 添加条目 条件完全相同的条目会被替换
+func (m *MemoryKeyProvider) Add(e KeyEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e.UID = append([]byte(nil), e.UID...)
+	e.Key = append([]byte(nil), e.Key...)
+	for i, exist := range m.entries {
+		if exist.Product == e.Product && exist.Slot == e.Slot && exist.Role == e.Role && bytes.Equal(exist.UID, e.UID) {
+			m.entries[i] = e
+			return
+		}
+	}
+	m.entries = append(m.entries, e)
+}
+
+// Entries 所有条目的副本
+func (m *MemoryKeyProvider) Entries() []KeyEntry {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]KeyEntry(nil), m.entries...)
+}
+
+func (m *MemoryKeyProvider) Key(req KeyRequest) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	best, bestScore := -1, -1
+	for i := range m.entries {
+		if score := m.entries[i].match(req); score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	if best < 0 {
+		return nil, ErrKeyNotFound
+	}
+	return append([]byte(nil), m.entries[best].Key...), nil
+}
+
+// pbkdf2 按照RFC 8018计算PBKDF2
+func pbkdf2(h func() hash.Hash, password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(h, password)
+	size := prf.Size()
+	var dk []byte
+	for block := uint32(1); len(dk) < keyLen; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write([]byte{byte(block >> 24), byte(block >> 16), byte(block >> 8), byte(block)})
+		u := prf.Sum(nil)
+		t := append([]byte(nil), u...)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := 0; j < size; j++ {
+				t[j] ^= u[j]
+			}
+		}
+		dk = append(dk, t...)
+	}
+	return dk[:keyLen]
+}
+
+// 加密密钥文件的格式: magic | salt(16) | iterations(4, 大端) | nonce(12) | AES-256-GCM密文
+// 文件头同时作为GCM的附加数据 密文为条目的JSON
+var keyStoreMagic = []byte("PN532KS1")
+
+const (
+	keyStoreSaltSize = 16
+	keyStoreHeader   = 8 + keyStoreSaltSize + 4
+	// DefaultKeyStoreIterations 由口令推导密钥时PBKDF2-SHA256的迭代次数
+	DefaultKeyStoreIterations = 200000
+)
+
+// FileKeyStore 保存在加密文件中的密钥 密钥由口令通过PBKDF2-SHA256推导 使用AES-256-GCM加密
+type FileKeyStore struct {
+	*MemoryKeyProvider
+	// Iterations 保存时使用的迭代次数 为0时使用 DefaultKeyStoreIterations
+	Iterations int
+
+	path       string
+	passphrase []byte
+}
+
+// OpenFileKeyStore 打开加密密钥文件 文件不存在时返回空的密钥库 调用Save后才会创建文件
+func OpenFileKeyStore(path string, passphrase []byte) (*FileKeyStore, error) {
+	s := &FileKeyStore{
+		MemoryKeyProvider: NewMemoryKeyProvider(),
+		path:              path,
+		passphrase:        append([]byte(nil), passphrase...),
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	entries, iterations, err := decryptKeyStore(data, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	s.Iterations = iterations
+	for _, e := range entries {
+		s.Add(e)
+	}
+	return s, nil
+}
+
+// Save 加密后写入文件 每次保存都会使用新的salt与nonce
+func (s *FileKeyStore) Save() error {
+	iterations := s.Iterations
+	if iterations <= 0 {
+		iterations = DefaultKeyStoreIterations
+	}
+	data, err := encryptKeyStore(s.Entries(), s.passphrase, iterations)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0600)
+}
+
+func keyStoreAEAD(passphrase, salt []byte, iterations int) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(pbkdf2(sha256.New, passphrase, salt, iterations, 32))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encryptKeyStore(entries []KeyEntry, passphrase []byte, iterations int) ([]byte, error) {
+	plain, err := json.Marshal(entries)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, keyStoreHeader)
+	copy(header, keyStoreMagic)
+	salt := header[8 : 8+keyStoreSaltSize]
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	binary.BigEndian.PutUint32(header[8+keyStoreSaltSize:], uint32(iterations))
+	aead, err := keyStoreAEAD(passphrase, salt, iterations)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out := append(header, nonce...)
+	return aead.Seal(out, nonce, plain, header), nil
+}
+
+func decryptKeyStore(data, passphrase []byte) ([]KeyEntry, int, error) {
+	if len(data) < keyStoreHeader || !bytes.Equal(data[:8], keyStoreMagic) {
+		return nil, 0, errors.New("not a key store file")
+	}
+	header := data[:keyStoreHeader]
+	salt := header[8 : 8+keyStoreSaltSize]
+	iterations := int(binary.BigEndian.Uint32(header[8+keyStoreSaltSize:]))
+	if iterations <= 0 {
+		return nil, 0, errors.New("invalid key store iterations")
+	}
+	aead, err := keyStoreAEAD(passphrase, salt, iterations)
+	if err != nil {
+		return nil, 0, err
+	}
+	rest := data[keyStoreHeader:]
+	if len(rest) < aead.NonceSize() {
+		return nil, 0, errors.New("key store file truncated")
+	}
+	plain, err := aead.Open(nil, rest[:aead.NonceSize()], rest[aead.NonceSize():], header)
+	if err != nil {
+		return nil, 0, errors.New("wrong passphrase or corrupted key store")
+	}
+	var entries []KeyEntry
+	if err := json.Unmarshal(plain, &entries); err != nil {
+		return nil, 0, err
+	}
+	return entries, iterations, nil
+}
+
+// MifareClassicAuthenticateWithProvider 从密钥源取得密码后认证扇区
+func (p *Pn532) MifareClassicAuthenticateWithProvider(t *TargetA, g ClassicGeometry, sector int, keyType byte, kp KeyProvider) error {
+	role := KeyRoleA
+	if keyType == command.MifareCmdAuthB {
+		role = KeyRoleB
+	}
+	info, _ := identifyATQASAK(t)
+	key, err := kp.Key(KeyRequest{Product: info.Product, UID: t.UID, Slot: sector, Role: role})
+	if err != nil {
+		return err
+	}
+	return p.MifareClassicAuthenticateSector(t, g, sector, keyType, key)
+}
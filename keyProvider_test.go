@@ -0,0 +1,93 @@
+package pn532
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+)
+
+func TestPBKDF2(t *testing.T) {
+	// RFC 7914 第11节
+	dk := pbkdf2(sha256.New, []byte("passwd"), []byte("salt"), 1, 64)
+	if !bytes.Equal(dk, mustHex("55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783")) {
+		t.Errorf("unexpected key: %x", dk)
+	}
+	dk = pbkdf2(sha256.New, []byte("password"), []byte("salt"), 4096, 32)
+	if !bytes.Equal(dk, mustHex("c5e478d59288c841aa530db6845c4c8d962893a001ce4e11a4963873aa98134a")) {
+		t.Errorf("unexpected key: %x", dk)
+	}
+}
+
+func TestMemoryKeyProvider(t *testing.T) {
+	uid := []byte{0xDE, 0xAD, 0xBE, 0xEF}
+	m := NewMemoryKeyProvider(
+		KeyEntry{Slot: AnySlot, Role: KeyRoleA, Key: []byte{0x01}},
+		KeyEntry{Product: ProductMifareClassic1K, Slot: 3, Role: KeyRoleA, Key: []byte{0x02}},
+		KeyEntry{UID: uid, Slot: AnySlot, Role: KeyRoleA, Key: []byte{0x03}},
+	)
+	for _, tc := range []struct {
+		req  KeyRequest
+		want byte
+	}{
+		{KeyRequest{Product: ProductMifareClassic4K, Slot: 3, Role: KeyRoleA}, 0x01},
+		{KeyRequest{Product: ProductMifareClassic1K, Slot: 3, Role: KeyRoleA}, 0x02},
+		{KeyRequest{Product: ProductMifareClassic1K, UID: uid, Slot: 3, Role: KeyRoleA}, 0x03},
+	} {
+		key, err := m.Key(tc.req)
+		if err != nil || len(key) != 1 || key[0] != tc.want {
+			t.Errorf("%+v: % X %v", tc.req, key, err)
+		}
+	}
+	if _, err := m.Key(KeyRequest{Role: KeyRoleB}); err != ErrKeyNotFound {
+		t.Errorf("unexpected error: %v", err)
+	}
+	m.Add(KeyEntry{Slot: AnySlot, Role: KeyRoleA, Key: []byte{0x04}})
+	if len(m.Entries()) != 3 {
+		t.Error("entry not replaced")
+	}
+}
+
+func TestKeyEntryJSON(t *testing.T) {
+	var entries []KeyEntry
+	if err := json.Unmarshal([]byte(`[{"role": "A", "key": "AQIDBAUG"}, {"slot": 0, "role": "B", "key": "AQIDBAUG"}]`), &entries); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Slot != AnySlot || entries[1].Slot != 0 {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+	m := NewMemoryKeyProvider(entries...)
+	if key, err := m.Key(KeyRequest{Slot: 5, Role: KeyRoleA}); err != nil || !bytes.Equal(key, []byte{1, 2, 3, 4, 5, 6}) {
+		t.Errorf("entry without slot should match any sector: % X %v", key, err)
+	}
+	if _, err := m.Key(KeyRequest{Slot: 5, Role: KeyRoleB}); err != ErrKeyNotFound {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestFileKeyStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.bin")
+	s, err := OpenFileKeyStore(path, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Iterations = 1000
+	key := []byte{0xA0, 0xA1, 0xA2, 0xA3, 0xA4, 0xA5}
+	s.Add(KeyEntry{Product: ProductMifareClassic1K, Slot: 1, Role: KeyRoleB, Key: key})
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = OpenFileKeyStore(path, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.Key(KeyRequest{Product: ProductMifareClassic1K, Slot: 1, Role: KeyRoleB})
+	if err != nil || !bytes.Equal(got, key) || s.Iterations != 1000 {
+		t.Errorf("unexpected key: % X %v", got, err)
+	}
+	if _, err := OpenFileKeyStore(path, []byte("wrong")); err == nil {
+		t.Error("wrong passphrase accepted")
+	}
+}
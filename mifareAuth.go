@@ -0,0 +1,63 @@
+package pn532
+
+import (
+	"errors"
+	"github.com/asjdf/pn532/command"
+)
+
+// ClassicAuthUID 返回MIFARE Classic认证使用的4字节UID
+// 4字节UID直接使用 7字节与10字节UID使用最后一级级联的4个字节
+func ClassicAuthUID(uid []byte) ([]byte, error) {
+	switch len(uid) {
+	case 4, 7, 10:
+		return uid[len(uid)-4:], nil
+	}
+	return nil, errors.New("uid length must be 4, 7 or 10")
+}
+
+// classicAuthCommand 构造认证命令 不包括InDataExchange与Tg
+func classicAuthCommand(uid []byte, blockNum byte, keyType byte, key []byte) ([]byte, error) {
+	if len(key) != 6 {
+		return nil, errors.New("key length must be 6")
+	}
+	if keyType != command.MifareCmdAuthA && keyType != command.MifareCmdAuthB {
+		return nil, errors.New("keyType must be 0x60 or 0x61")
+	}
+	authUID, err := ClassicAuthUID(uid)
+	if err != nil {
+		return nil, err
+	}
+	cmd := []byte{keyType, blockNum}
+	cmd = append(cmd, key...)
+	return append(cmd, authUID...), nil
+}
+
+// MifareClassicAuthenticate 使用目标的UID验证区块密码 认证失败时返回 StatusMifareAuth 等 StatusError
+// 认证成功后可以直接认证其他扇区 无需重新识读
+// 认证失败后卡片会进入HALT状态 此时会自动重新激活目标 以便继续尝试其他密码或扇区
+func (p *Pn532) MifareClassicAuthenticate(t *TargetA, blockNum byte, keyType byte, key []byte) error {
+	if t == nil || p.current != Target(t) || t.State() != TargetSelected {
+		return ErrNoTarget
+	}
+	cmd, err := classicAuthCommand(t.UID, blockNum, keyType, key)
+	if err != nil {
+		return err
+	}
+	_, err = p.InDataExchange(cmd)
+	var status StatusError
+	if errors.As(err, &status) {
+		if reErr := p.Reactivate(t); reErr != nil {
+			return reErr
+		}
+	}
+	return err
+}
+
+// MifareClassicAuthenticateSector 按扇区验证密码 使用扇区的区块尾作为认证块
+func (p *Pn532) MifareClassicAuthenticateSector(t *TargetA, g ClassicGeometry, sector int, keyType byte, key []byte) error {
+	trailer, err := g.TrailerBlock(sector)
+	if err != nil {
+		return err
+	}
+	return p.MifareClassicAuthenticate(t, trailer, keyType, key)
+}
@@ -0,0 +1,36 @@
+package pn532
+
+import (
+	"bytes"
+	"github.com/asjdf/pn532/command"
+	"testing"
+)
+
+func TestClassicAuthUID(t *testing.T) {
+	uid7 := []byte{0x04, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66}
+	if uid, err := ClassicAuthUID(uid7); err != nil || !bytes.Equal(uid, []byte{0x33, 0x44, 0x55, 0x66}) {
+		t.Errorf("unexpected uid: % X %v", uid, err)
+	}
+	if uid, err := ClassicAuthUID(uid7[3:]); err != nil || !bytes.Equal(uid, uid7[3:]) {
+		t.Errorf("unexpected uid: % X %v", uid, err)
+	}
+	if _, err := ClassicAuthUID(uid7[:5]); err == nil {
+		t.Error("5 byte uid accepted")
+	}
+
+	key := []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+	cmd, err := classicAuthCommand(uid7, 0x3B, command.MifareCmdAuthB, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0x61, 0x3B, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x33, 0x44, 0x55, 0x66}
+	if !bytes.Equal(cmd, want) {
+		t.Errorf("unexpected command: % X", cmd)
+	}
+	if _, err := classicAuthCommand(uid7, 0x3B, 0x30, key); err == nil {
+		t.Error("invalid key type accepted")
+	}
+	if _, err := classicAuthCommand(uid7, 0x3B, command.MifareCmdAuthA, key[:5]); err == nil {
+		t.Error("short key accepted")
+	}
+}
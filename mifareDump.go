@@ -0,0 +1,344 @@
+package pn532
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/asjdf/pn532/command"
+)
+
+// ClassicKeySet 转储或恢复时尝试的密码 每个密码会分别作为密码A与密码B尝试
+type ClassicKeySet struct {
+	Common  [][]byte         // 所有扇区都尝试的密码
+	Sectors map[int][][]byte // 特定扇区优先尝试的密码
+}
+
+// DefaultClassicKeys 常见的出厂密码
+var DefaultClassicKeys = [][]byte{
+	{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF},
+	{0xA0, 0xA1, 0xA2, 0xA3, 0xA4, 0xA5},
+	{0xD3, 0xF7, 0xD3, 0xF7, 0xD3, 0xF7},
+	{0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+}
+
+// keysFor 扇区需要尝试的密码 去除重复
+func (k *ClassicKeySet) keysFor(sector int) [][]byte {
+	if k == nil {
+		return nil
+	}
+	var keys [][]byte
+	add := func(key []byte) {
+		if len(key) != 6 {
+			return
+		}
+		for _, exist := range keys {
+			if bytes.Equal(exist, key) {
+				return
+			}
+		}
+		keys = append(keys, key)
+	}
+	for _, key := range k.Sectors[sector] {
+		add(key)
+	}
+	for _, key := range k.Common {
+		add(key)
+	}
+	return keys
+}
+
+// ClassicSectorKeys 打开扇区的密码 未找到时为nil
+type ClassicSectorKeys struct {
+	KeyA []byte
+	KeyB []byte
+}
+
+// ClassicDump MIFARE Classic 卡片的完整转储
+type ClassicDump struct {
+	UID      []byte
+	ATQA     [2]byte
+	SAK      byte
+	Geometry ClassicGeometry
+	// Blocks 按块号排列的数据 无法读取的块为nil
+	// 区块尾中的密码A(以及不可读的密码B)会用找到的密码填充
+	Blocks  [][]byte
+	Sectors []ClassicSectorKeys
+}
+
+// Missing 无法读取的块号
+func (d *ClassicDump) Missing() []byte {
+	var missing []byte
+	for i, block := range d.Blocks {
+		if block == nil {
+			missing = append(missing, byte(i))
+		}
+	}
+	return missing
+}
+
+// Complete 是否所有块都已读取
+func (d *ClassicDump) Complete() bool {
+	return len(d.Missing()) == 0
+}
+
+// isStatusError 卡片拒绝了命令 卡片会回到IDLE状态 需要重新激活
+func isStatusError(err error) bool {
+	var status StatusError
+	return errors.As(err, &status)
+}
+
+// findClassicKey 依次尝试密码 返回认证成功的密码 都失败时返回nil
+func (p *Pn532) findClassicKey(t *TargetA, g ClassicGeometry, sector int, keyType byte, keys [][]byte) ([]byte, error) {
+	for _, key := range keys {
+		err := p.MifareClassicAuthenticateSector(t, g, sector, keyType, key)
+		if err == nil {
+			return key, nil
+		}
+		if !isStatusError(err) {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// DumpClassic 转储MIFARE Classic卡片 扇区布局由SAK确定
+// 每个扇区依次尝试keys中的密码A与密码B 记录打开扇区的密码 无法读取的块记为nil
+// 只有通信错误才会返回error 密码错误或块不可读不会中止转储
+func (p *Pn532) DumpClassic(t *TargetA, keys *ClassicKeySet) (*ClassicDump, error) {
+	g, err := ClassicGeometryFromSAK(t.SAK)
+	if err != nil {
+		return nil, err
+	}
+	dump := &ClassicDump{
+		UID:      append([]byte(nil), t.UID...),
+		ATQA:     t.ATQA,
+		SAK:      t.SAK,
+		Geometry: g,
+		Blocks:   make([][]byte, g.Blocks()),
+		Sectors:  make([]ClassicSectorKeys, g.Sectors),
+	}
+	for sector := 0; sector < g.Sectors; sector++ {
+		if err := p.dumpClassicSector(t, dump, sector, keys.keysFor(sector)); err != nil {
+			return dump, fmt.Errorf("sector %d: %w", sector, err)
+		}
+	}
+	return dump, nil
+}
+
+func (p *Pn532) dumpClassicSector(t *TargetA, dump *ClassicDump, sector int, keys [][]byte) error {
+	g := dump.Geometry
+	sectorKeys := &dump.Sectors[sector]
+	var err error
+	if sectorKeys.KeyA, err = p.findClassicKey(t, g, sector, command.MifareCmdAuthA, keys); err != nil {
+		return err
+	}
+	if sectorKeys.KeyB, err = p.findClassicKey(t, g, sector, command.MifareCmdAuthB, keys); err != nil {
+		return err
+	}
+
+	for _, auth := range []struct {
+		keyType byte
+		key     []byte
+	}{{command.MifareCmdAuthA, sectorKeys.KeyA}, {command.MifareCmdAuthB, sectorKeys.KeyB}} {
+		if auth.key == nil {
+			continue
+		}
+		authenticated := false
+		for _, blockNum := range g.SectorBlocks(sector) {
+			if dump.Blocks[blockNum] != nil {
+				continue
+			}
+			if !authenticated {
+				if err := p.MifareClassicAuthenticateSector(t, g, sector, auth.keyType, auth.key); err != nil {
+					if isStatusError(err) {
+						break
+					}
+					return err
+				}
+				authenticated = true
+			}
+			data, err := p.InDataExchange([]byte{command.MifareCmdRead, blockNum})
+			if err != nil {
+				if !isStatusError(err) {
+					return err
+				}
+				// 块不可读 卡片已回到IDLE状态
+				if err := p.Reactivate(t); err != nil {
+					return err
+				}
+				authenticated = false
+				continue
+			}
+			dump.Blocks[blockNum] = data
+		}
+	}
+
+	trailerNum, _ := g.TrailerBlock(sector)
+	if trailer := dump.Blocks[trailerNum]; len(trailer) == 16 {
+		if sectorKeys.KeyA != nil {
+			copy(trailer[0:6], sectorKeys.KeyA)
+		}
+		if sectorKeys.KeyB != nil {
+			copy(trailer[10:16], sectorKeys.KeyB)
+		}
+	}
+	return nil
+}
+
+// ClassicRestoreOptions 恢复转储时的选项
+type ClassicRestoreOptions struct {
+	// WriteTrailers 写入区块尾 写入前会按照TrailerOptions检查
+	WriteTrailers  bool
+	TrailerOptions TrailerWriteOptions
+	// WriteBlock0 写入块0 仅适用于块0可写的测试卡(CUID) 正常卡片会拒绝写入
+	WriteBlock0 bool
+	// DryRun 只检查转储并列出将要写入的块 不与卡片通信
+	DryRun bool
+}
+
+// ClassicRestoreReport 恢复的结果
+type ClassicRestoreReport struct {
+	Written []byte         // 写入并校验成功的块 DryRun时为将要写入的块
+	Skipped []byte         // 按选项跳过或转储中缺失的块
+	Failed  map[byte]error // 写入或校验失败的块
+}
+
+// RestoreClassic 将转储写入卡片 每个块写入后都会读回校验
+// keys 为目标卡片当前的密码 之后还会尝试转储中记录的密码
+// 同一扇区中区块尾最后写入 只有通信错误才会返回error
+func (p *Pn532) RestoreClassic(t *TargetA, dump *ClassicDump, keys *ClassicKeySet, opt ClassicRestoreOptions) (*ClassicRestoreReport, error) {
+	g := dump.Geometry
+	if len(dump.Blocks) != g.Blocks() {
+		return nil, errors.New("dump does not match its geometry")
+	}
+	if !opt.DryRun && t.SAK != dump.SAK {
+		if tg, err := ClassicGeometryFromSAK(t.SAK); err != nil || tg.Blocks() < g.Blocks() {
+			return nil, errors.New("target is smaller than the dump")
+		}
+	}
+	report := &ClassicRestoreReport{Failed: map[byte]error{}}
+	for sector := 0; sector < g.Sectors; sector++ {
+		var blocks []byte
+		for _, blockNum := range g.SectorBlocks(sector) {
+			data := dump.Blocks[blockNum]
+			switch {
+			case data == nil,
+				blockNum == 0 && !opt.WriteBlock0,
+				g.IsTrailer(blockNum) && !opt.WriteTrailers:
+				report.Skipped = append(report.Skipped, blockNum)
+				continue
+			}
+			if len(data) != 16 {
+				report.Failed[blockNum] = errors.New("data length must be 16")
+				continue
+			}
+			if g.IsTrailer(blockNum) {
+				if err := CheckSectorTrailer(data, opt.TrailerOptions); err != nil {
+					report.Failed[blockNum] = err
+					continue
+				}
+			}
+			blocks = append(blocks, blockNum)
+		}
+		if opt.DryRun {
+			report.Written = append(report.Written, blocks...)
+			continue
+		}
+		if len(blocks) == 0 {
+			continue
+		}
+		candidates := keys.keysFor(sector)
+		if sector < len(dump.Sectors) {
+			extra := &ClassicKeySet{Common: [][]byte{dump.Sectors[sector].KeyA, dump.Sectors[sector].KeyB}}
+			candidates = append(candidates, extra.keysFor(sector)...)
+		}
+		for _, blockNum := range blocks {
+			err := p.restoreClassicBlock(t, g, sector, blockNum, dump.Blocks[blockNum], candidates)
+			if err != nil && !isStatusError(err) && !errors.Is(err, errVerifyFailed) && !errors.Is(err, errNoWriteKey) {
+				return report, fmt.Errorf("block %#02X: %w", blockNum, err)
+			}
+			if err != nil {
+				report.Failed[blockNum] = err
+			} else {
+				report.Written = append(report.Written, blockNum)
+			}
+		}
+	}
+	return report, nil
+}
+
+var (
+	errVerifyFailed = errors.New("verification failed")
+	errNoWriteKey   = errors.New("no key allows writing this block")
+)
+
+// restoreClassicBlock 依次尝试密码B与密码A写入块 并读回校验
+func (p *Pn532) restoreClassicBlock(t *TargetA, g ClassicGeometry, sector int, blockNum byte, data []byte, keys [][]byte) error {
+	for _, keyType := range []byte{command.MifareCmdAuthB, command.MifareCmdAuthA} {
+		for _, key := range keys {
+			if err := p.MifareClassicAuthenticateSector(t, g, sector, keyType, key); err != nil {
+				if isStatusError(err) {
+					continue
+				}
+				return err
+			}
+			if _, err := p.InDataExchange(append([]byte{command.MifareCmdWrite, blockNum}, data...)); err != nil {
+				if !isStatusError(err) {
+					return err
+				}
+				if err := p.Reactivate(t); err != nil {
+					return err
+				}
+				continue
+			}
+			if g.IsTrailer(blockNum) {
+				// 密码可能已经改变 用新的密码认证后再读回
+				return p.verifyClassicTrailer(t, g, sector, blockNum, data)
+			}
+			got, err := p.InDataExchange([]byte{command.MifareCmdRead, blockNum})
+			if err != nil {
+				if isStatusError(err) {
+					if reErr := p.Reactivate(t); reErr != nil {
+						return reErr
+					}
+				}
+				return err
+			}
+			if !bytes.Equal(got, data) {
+				return errVerifyFailed
+			}
+			return nil
+		}
+	}
+	return errNoWriteKey
+}
+
+// verifyClassicTrailer 校验区块尾 密码A不可读 只比较访问控制位与GPB
+func (p *Pn532) verifyClassicTrailer(t *TargetA, g ClassicGeometry, sector int, blockNum byte, data []byte) error {
+	for _, auth := range []struct {
+		keyType byte
+		key     []byte
+	}{{command.MifareCmdAuthA, data[0:6]}, {command.MifareCmdAuthB, data[10:16]}} {
+		if err := p.MifareClassicAuthenticateSector(t, g, sector, auth.keyType, auth.key); err != nil {
+			if isStatusError(err) {
+				continue
+			}
+			return err
+		}
+		got, err := p.InDataExchange([]byte{command.MifareCmdRead, blockNum})
+		if err != nil {
+			if isStatusError(err) {
+				if err := p.Reactivate(t); err != nil {
+					return err
+				}
+				continue
+			}
+			return err
+		}
+		if len(got) != 16 || !bytes.Equal(got[6:10], data[6:10]) {
+			return errVerifyFailed
+		}
+		return nil
+	}
+	return errVerifyFailed
+}
@@ -0,0 +1,154 @@
+package pn532
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testClassicDump() *ClassicDump {
+	d := newClassicDump(Classic1K)
+	d.UID = []byte{0xDE, 0xAD, 0xBE, 0xEF}
+	d.ATQA = [2]byte{0x00, 0x04}
+	d.SAK = 0x08
+	for i := range d.Blocks {
+		d.Blocks[i] = bytes.Repeat([]byte{byte(i)}, 16)
+	}
+	d.Blocks[0] = []byte{0xDE, 0xAD, 0xBE, 0xEF, 0x22, 0x08, 0x04, 0x00, 0x62, 0x63, 0x64, 0x65, 0x66, 0x67, 0x68, 0x69}
+	key := []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+	trailer := DefaultSectorTrailer()
+	trailer.Access[3] = AccessTrailerKeyB
+	for sector := 0; sector < d.Geometry.Sectors; sector++ {
+		n, _ := d.Geometry.TrailerBlock(sector)
+		d.Blocks[n] = trailer.Bytes()
+		d.Sectors[sector] = ClassicSectorKeys{KeyA: key, KeyB: key}
+	}
+	// 扇区1 只找到密码B 块5不可读
+	d.Sectors[1].KeyA = nil
+	copy(d.Blocks[7][0:6], make([]byte, 6))
+	d.Blocks[5] = nil
+	return d
+}
+
+func TestClassicDumpFormats(t *testing.T) {
+	d := testClassicDump()
+
+	mct := string(d.MCT())
+	if !strings.Contains(mct, "+Sector: 1\n04040404040404040404040404040404\n--------------------------------\n") ||
+		!strings.Contains(mct, "------------7F078869FFFFFFFFFFFF\n") {
+		t.Errorf("unexpected mct:\n%s", mct)
+	}
+	nfc := string(d.FlipperNFC())
+	if !strings.Contains(nfc, "UID: DE AD BE EF\n# ISO14443-3A specific data\nATQA: 00 04\nSAK: 08\n") ||
+		!strings.Contains(nfc, "Block 7: ?? ?? ?? ?? ?? ?? 7F 07 88 69 FF FF FF FF FF FF\n") {
+		t.Errorf("unexpected nfc:\n%s", nfc)
+	}
+	pm, err := d.ProxmarkJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(pm), `"ATQA": "0400"`) {
+		t.Errorf("unexpected json:\n%s", pm)
+	}
+
+	for name, parse := range map[string]func() (*ClassicDump, error){
+		"mct":  func() (*ClassicDump, error) { return ParseClassicMCT([]byte(mct)) },
+		"nfc":  func() (*ClassicDump, error) { return ParseClassicFlipperNFC([]byte(nfc)) },
+		"json": func() (*ClassicDump, error) { return ParseClassicProxmarkJSON(pm) },
+	} {
+		got, err := parse()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(got, d) {
+			t.Errorf("%s: round trip mismatch\n%+v\n%+v", name, got, d)
+		}
+	}
+
+	// 二进制格式无法表示未知数据
+	bin := d.Bin()
+	if len(bin) != 1024 || !bytes.Equal(bin[5*16:6*16], make([]byte, 16)) {
+		t.Error("unexpected bin")
+	}
+	got, err := ParseClassicEML(d.EML())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Bin(), bin) || !bytes.Equal(got.UID, d.UID) || got.ATQA != d.ATQA || got.SAK != d.SAK {
+		t.Errorf("eml round trip mismatch: % X %v %02X", got.UID, got.ATQA, got.SAK)
+	}
+	if got.Sectors[1].KeyA == nil || !got.Complete() {
+		t.Error("bin keys should be known")
+	}
+}
+
+func TestClassicDumpFiles(t *testing.T) {
+	d := testClassicDump()
+	dir := t.TempDir()
+	for _, ext := range ClassicDumpExtensions() {
+		path := filepath.Join(dir, "dump"+ext)
+		if err := SaveClassicDump(path, d); err != nil {
+			t.Fatalf("%s: %v", ext, err)
+		}
+		got, err := LoadClassicDump(path)
+		if err != nil {
+			t.Fatalf("%s: %v", ext, err)
+		}
+		if !bytes.Equal(got.Bin(), d.Bin()) {
+			t.Errorf("%s: round trip mismatch", ext)
+		}
+	}
+	if err := SaveClassicDump(filepath.Join(dir, "dump.txt"), d); err == nil {
+		t.Error("unknown extension accepted")
+	}
+}
+
+func TestClassicDumpPartialGeometry(t *testing.T) {
+	// 只导出了前4个扇区的1K卡片 按最大的块号推断会误判为Mini
+	mct := string(testClassicDump().MCT())
+	mct = mct[:strings.Index(mct, "+Sector: 4\n")]
+	d, err := ParseClassicMCT([]byte(mct))
+	if err != nil || d.Geometry != Classic1K {
+		t.Errorf("mct: unexpected geometry: %+v %v", d, err)
+	}
+	// 块0未知时只能按扇区号推断
+	mct = strings.Replace(mct, "DEADBEEF", "--------", 1)
+	if d, err := ParseClassicMCT([]byte(mct)); err != nil || d.Geometry != ClassicMini {
+		t.Errorf("mct: unexpected geometry: %+v %v", d, err)
+	}
+
+	for _, c := range []struct {
+		card string
+		want ClassicGeometry
+	}{
+		{`"UID": "DEADBEEF", "ATQA": "0400", "SAK": "08"`, Classic1K},
+		{`"UID": "DEADBEEF", "ATQA": "0200", "SAK": "18"`, Classic4K},
+		{`"UID": "DEADBEEF", "ATQA": "0200"`, Classic4K},
+		{`"UID": "DEADBEEF", "ATQA": "0400", "SAK": "09"`, Classic1K}, // 块40超出Mini的容量
+		{`"UID": "DEADBEEF"`, Classic1K},
+	} {
+		pm := `{"Card": {` + c.card + `}, "blocks": {"0": "DEADBEEF220804006263646566676869", "40": "28282828282828282828282828282828"}}`
+		d, err := ParseClassicProxmarkJSON([]byte(pm))
+		if err != nil {
+			t.Errorf("%s: %v", c.card, err)
+			continue
+		}
+		if d.Geometry != c.want || !bytes.Equal(d.Blocks[40], bytes.Repeat([]byte{0x28}, 16)) {
+			t.Errorf("%s: unexpected geometry: %+v", c.card, d.Geometry)
+		}
+	}
+}
+
+func TestParseBlock0SevenByteUID(t *testing.T) {
+	bin := make([]byte, 320)
+	copy(bin, []byte{0x04, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x08, 0x44, 0x00})
+	d, err := ParseClassicBin(bin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Geometry != ClassicMini || len(d.UID) != 7 || d.SAK != 0x08 || d.ATQA != [2]byte{0x00, 0x44} {
+		t.Errorf("unexpected dump: % X %02X %v", d.UID, d.SAK, d.ATQA)
+	}
+}
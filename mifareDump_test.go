@@ -0,0 +1,255 @@
+package pn532
+
+import (
+	"bytes"
+	"github.com/asjdf/pn532/command"
+	"testing"
+)
+
+// fakeClassic 通过fakePort模拟PN532与一张MIFARE Classic Mini卡片
+// 认证 读取或写入失败后卡片进入HALT状态 需要通过InListPassiveTarget重新选择
+type fakeClassic struct {
+	uid        []byte
+	blocks     [][]byte
+	keys       [][2][]byte // 每个扇区的密码A与密码B
+	unreadable map[byte]bool
+	readOnly   map[byte]bool
+	authed     int // 已认证的扇区 -1表示未认证
+	halted     bool
+	reactive   int
+	writes     []byte
+}
+
+func newFakeClassic() *fakeClassic {
+	f := &fakeClassic{
+		uid:        []byte{0xDE, 0xAD, 0xBE, 0xEF},
+		blocks:     make([][]byte, ClassicMini.Blocks()),
+		keys:       make([][2][]byte, ClassicMini.Sectors),
+		unreadable: map[byte]bool{},
+		readOnly:   map[byte]bool{},
+		authed:     -1,
+	}
+	for i := range f.blocks {
+		f.blocks[i] = bytes.Repeat([]byte{byte(i)}, 16)
+	}
+	f.blocks[0] = mustHex("DEADBEEF22080400" + "6263646566676869")
+	for sector := range f.keys {
+		f.keys[sector] = [2][]byte{DefaultClassicKeys[0], DefaultClassicKeys[0]}
+		trailer, _ := ClassicMini.TrailerBlock(sector)
+		f.blocks[trailer] = DefaultSectorTrailer().Bytes()
+	}
+	return f
+}
+
+// target 返回已选择的目标
+func (f *fakeClassic) target() (*Pn532, *TargetA) {
+	p, _ := newFakePn532(f.handle)
+	t := &TargetA{targetBase: targetBase{tg: 0x01}, ATQA: [2]byte{0x00, 0x04}, SAK: 0x09, UID: f.uid}
+	p.trackTargets([]Target{t})
+	return p, t
+}
+
+func (f *fakeClassic) handle(cmd []byte) []byte {
+	switch cmd[0] {
+	case command.InListPassiveTarget:
+		if !bytes.Equal(cmd[3:], f.uid) {
+			return []byte{cmd[0] + 1, 0x00}
+		}
+		f.halted, f.authed = false, -1
+		f.reactive++
+		return append([]byte{cmd[0] + 1, 0x01, 0x01, 0x00, 0x04, 0x09, 0x04}, f.uid...)
+	case command.InDataExchange:
+		if f.halted {
+			return []byte{cmd[0] + 1, byte(StatusTimeout)}
+		}
+		data, ok := f.exchange(cmd[2:])
+		if !ok {
+			f.halted, f.authed = true, -1
+			return []byte{cmd[0] + 1, byte(StatusMifareAuth)}
+		}
+		return append([]byte{cmd[0] + 1, 0x00}, data...)
+	}
+	return []byte{cmd[0] + 1}
+}
+
+func (f *fakeClassic) exchange(c []byte) ([]byte, bool) {
+	blockNum := c[1]
+	sector, _, _ := ClassicMini.Sector(blockNum)
+	switch c[0] {
+	case command.MifareCmdAuthA, command.MifareCmdAuthB:
+		key := f.keys[sector][c[0]-command.MifareCmdAuthA]
+		if !bytes.Equal(c[2:8], key) || !bytes.Equal(c[8:12], f.uid) {
+			return nil, false
+		}
+		f.authed = sector
+		return nil, true
+	case command.MifareCmdRead:
+		if f.authed != sector || f.unreadable[blockNum] {
+			return nil, false
+		}
+		data := append([]byte(nil), f.blocks[blockNum]...)
+		if ClassicMini.IsTrailer(blockNum) {
+			copy(data[0:6], make([]byte, 6)) // 密码A不可读
+		}
+		return data, true
+	case command.MifareCmdWrite:
+		if f.authed != sector || f.readOnly[blockNum] || len(c) != 18 {
+			return nil, false
+		}
+		f.blocks[blockNum] = append([]byte(nil), c[2:]...)
+		f.writes = append(f.writes, blockNum)
+		return nil, true
+	}
+	return nil, false
+}
+
+func TestClassicKeySet(t *testing.T) {
+	keys := &ClassicKeySet{
+		Common: DefaultClassicKeys,
+		Sectors: map[int][][]byte{
+			1: {{0x01, 0x02, 0x03, 0x04, 0x05, 0x06}, DefaultClassicKeys[0], {0x01}},
+		},
+	}
+	if got := keys.keysFor(0); len(got) != len(DefaultClassicKeys) {
+		t.Errorf("unexpected keys: %v", got)
+	}
+	got := keys.keysFor(1)
+	if len(got) != len(DefaultClassicKeys)+1 || got[0][0] != 0x01 || !bytes.Equal(got[1], DefaultClassicKeys[0]) {
+		t.Errorf("unexpected keys: % X", got)
+	}
+	if (*ClassicKeySet)(nil).keysFor(0) != nil {
+		t.Error("nil key set has keys")
+	}
+}
+
+func TestRestoreClassicDryRun(t *testing.T) {
+	dump := &ClassicDump{Geometry: ClassicMini, Blocks: make([][]byte, ClassicMini.Blocks())}
+	for i := range dump.Blocks {
+		dump.Blocks[i] = make([]byte, 16)
+	}
+	for sector := 0; sector < ClassicMini.Sectors; sector++ {
+		trailer, _ := ClassicMini.TrailerBlock(sector)
+		dump.Blocks[trailer] = DefaultSectorTrailer().Bytes()
+	}
+	dump.Blocks[5] = nil
+	locked := DefaultSectorTrailer()
+	locked.Access[3] = 0x07
+	dump.Blocks[7] = locked.Bytes()
+	if len(dump.Missing()) != 1 || dump.Complete() {
+		t.Errorf("unexpected missing blocks: % X", dump.Missing())
+	}
+
+	p := &Pn532{}
+	report, err := p.RestoreClassic(&TargetA{}, dump, nil, ClassicRestoreOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 块0 块5 以及5个区块尾被跳过
+	if len(report.Skipped) != 7 || len(report.Written) != 13 || len(report.Failed) != 0 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+
+	report, err = p.RestoreClassic(&TargetA{}, dump, nil, ClassicRestoreOptions{DryRun: true, WriteTrailers: true, WriteBlock0: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Failed[7] != ErrPermanentLock || len(report.Written) != 18 || len(report.Skipped) != 1 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
+
+func TestDumpClassic(t *testing.T) {
+	card := newFakeClassic()
+	keyB := []byte{0xB0, 0xB1, 0xB2, 0xB3, 0xB4, 0xB5}
+	card.keys[1] = [2][]byte{{0xA0, 0xA0, 0xA0, 0xA0, 0xA0, 0x01}, keyB}
+	card.keys[2][1] = []byte{0x0B, 0x0B, 0x0B, 0x0B, 0x0B, 0x0B}
+	card.keys[3] = [2][]byte{{0x03, 0x03, 0x03, 0x03, 0x03, 0x03}, {0x03, 0x03, 0x03, 0x03, 0x03, 0x04}}
+	card.unreadable[9] = true
+	p, target := card.target()
+
+	keys := &ClassicKeySet{Common: DefaultClassicKeys, Sectors: map[int][][]byte{1: {keyB}}}
+	dump, err := p.DumpClassic(target, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dump.Geometry != ClassicMini || !bytes.Equal(dump.UID, card.uid) || dump.SAK != 0x09 {
+		t.Errorf("unexpected dump: %+v", dump)
+	}
+	// 扇区0两个密码都找到 扇区1只找到密码B 扇区2只找到密码A 扇区3都没有找到
+	for sector, want := range [][2][]byte{
+		{DefaultClassicKeys[0], DefaultClassicKeys[0]},
+		{nil, keyB},
+		{DefaultClassicKeys[0], nil},
+		{nil, nil},
+	} {
+		got := dump.Sectors[sector]
+		if !bytes.Equal(got.KeyA, want[0]) || !bytes.Equal(got.KeyB, want[1]) || (got.KeyA == nil) != (want[0] == nil) {
+			t.Errorf("sector %d: unexpected keys: % X", sector, got)
+		}
+	}
+	// 块9不可读 扇区3无法打开
+	if missing := dump.Missing(); !bytes.Equal(missing, []byte{0x09, 0x0C, 0x0D, 0x0E, 0x0F}) {
+		t.Errorf("unexpected missing blocks: % X", missing)
+	}
+	if !bytes.Equal(dump.Blocks[0], card.blocks[0]) || !bytes.Equal(dump.Blocks[8], card.blocks[8]) || !bytes.Equal(dump.Blocks[17], card.blocks[17]) {
+		t.Error("unexpected block data")
+	}
+	// 区块尾中不可读的密码A用找到的密码填充
+	if !bytes.Equal(dump.Blocks[3], card.blocks[3]) || !bytes.Equal(dump.Blocks[7][0:6], make([]byte, 6)) || !bytes.Equal(dump.Blocks[7][10:16], keyB) {
+		t.Errorf("unexpected trailers: % X % X", dump.Blocks[3], dump.Blocks[7])
+	}
+	if target.State() != TargetSelected || p.CurrentTarget() != target {
+		t.Error("target not reactivated")
+	}
+	// 每次认证失败与读取失败都需要重新激活
+	if card.reactive < 12 {
+		t.Errorf("reactivated %d times", card.reactive)
+	}
+}
+
+func TestRestoreClassic(t *testing.T) {
+	src := newFakeClassic()
+	for i := range src.blocks {
+		if !ClassicMini.IsTrailer(byte(i)) && i != 0 {
+			src.blocks[i] = bytes.Repeat([]byte{0xC0 | byte(i)}, 16)
+		}
+	}
+	p, target := src.target()
+	dump, err := p.DumpClassic(target, &ClassicKeySet{Common: DefaultClassicKeys})
+	if err != nil || !dump.Complete() {
+		t.Fatalf("unexpected dump: % X %v", dump.Missing(), err)
+	}
+
+	card := newFakeClassic()
+	card.keys[1] = [2][]byte{DefaultClassicKeys[1], DefaultClassicKeys[1]}
+	card.readOnly[6] = true
+	block0 := append([]byte(nil), card.blocks[0]...)
+	p, target = card.target()
+	report, err := p.RestoreClassic(target, dump, &ClassicKeySet{Common: [][]byte{DefaultClassicKeys[1]}}, ClassicRestoreOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 块0与区块尾被跳过 块6所有密码都无法写入
+	if !bytes.Equal(report.Skipped, []byte{0x00, 0x03, 0x07, 0x0B, 0x0F, 0x13}) {
+		t.Errorf("unexpected skipped blocks: % X", report.Skipped)
+	}
+	if len(report.Failed) != 1 || report.Failed[6] != errNoWriteKey || len(report.Written) != 13 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	for _, blockNum := range card.writes {
+		if blockNum == 0 || ClassicMini.IsTrailer(blockNum) {
+			t.Errorf("block %#02X should not be written", blockNum)
+		}
+	}
+	for _, blockNum := range report.Written {
+		if !bytes.Equal(card.blocks[blockNum], src.blocks[blockNum]) {
+			t.Errorf("block %#02X: unexpected data % X", blockNum, card.blocks[blockNum])
+		}
+	}
+	if !bytes.Equal(card.blocks[0], block0) || bytes.Equal(card.blocks[6], src.blocks[6]) {
+		t.Error("block 0 or read-only block changed")
+	}
+	if !bytes.Equal(card.keys[1][0], DefaultClassicKeys[1]) {
+		t.Error("trailer written without WriteTrailers")
+	}
+}
@@ -0,0 +1,196 @@
+package pn532
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ClassicGeometry MIFARE Classic 卡片的扇区布局
+// 前32个扇区每扇区4块 之后的扇区(仅4K)每扇区16块 每个扇区的最后一块为区块尾
+type ClassicGeometry struct {
+	Sectors int
+}
+
+// 各型号的扇区布局
+var (
+	ClassicMini = ClassicGeometry{Sectors: 5}
+	Classic1K   = ClassicGeometry{Sectors: 16}
+	Classic2K   = ClassicGeometry{Sectors: 32}
+	Classic4K   = ClassicGeometry{Sectors: 40}
+)
+
+// 小扇区与大扇区的分界
+const (
+	smallSectors     = 32
+	smallSectorSize  = 4
+	largeSectorSize  = 16
+	smallSectorBlock = smallSectors * smallSectorSize
+)
+
+// ClassicGeometryFromSAK 根据SAK确定扇区布局
+func ClassicGeometryFromSAK(sak byte) (ClassicGeometry, error) {
+	switch sak {
+	case 0x09:
+		return ClassicMini, nil
+	case 0x08, 0x88, 0x28:
+		return Classic1K, nil
+	case 0x19:
+		return Classic2K, nil
+	case 0x18, 0x38:
+		return Classic4K, nil
+	}
+	return ClassicGeometry{}, fmt.Errorf("SAK %#02X is not a MIFARE Classic card", sak)
+}
+
+// ClassicGeometryFromCardInfo 根据 Identify 的识别结果确定扇区布局
+func ClassicGeometryFromCardInfo(info *CardInfo) (ClassicGeometry, error) {
+	switch info.Product {
+	case ProductMifareClassicMini:
+		return ClassicMini, nil
+	case ProductMifareClassic1K:
+		return Classic1K, nil
+	case ProductMifareClassic2K:
+		return Classic2K, nil
+	case ProductMifareClassic4K:
+		return Classic4K, nil
+	}
+	if info.Has(CapCrypto1) {
+		// SmartMX与MIFARE Plus SL1的模拟容量
+		switch info.MemorySize {
+		case 1024:
+			return Classic1K, nil
+		case 2048:
+			return Classic2K, nil
+		case 4096:
+			return Classic4K, nil
+		}
+	}
+	return ClassicGeometry{}, fmt.Errorf("%s is not a MIFARE Classic card", info.Product)
+}
+
+// Blocks 总块数
+func (g ClassicGeometry) Blocks() int {
+	if g.Sectors <= smallSectors {
+		return g.Sectors * smallSectorSize
+	}
+	return smallSectorBlock + (g.Sectors-smallSectors)*largeSectorSize
+}
+
+// BlocksInSector 扇区的块数
+func (g ClassicGeometry) BlocksInSector(sector int) int {
+	if sector < smallSectors {
+		return smallSectorSize
+	}
+	return largeSectorSize
+}
+
+// FirstBlock 扇区的第一个块号
+func (g ClassicGeometry) FirstBlock(sector int) (byte, error) {
+	if sector < 0 || sector >= g.Sectors {
+		return 0, fmt.Errorf("sector %d out of range", sector)
+	}
+	if sector < smallSectors {
+		return byte(sector * smallSectorSize), nil
+	}
+	return byte(smallSectorBlock + (sector-smallSectors)*largeSectorSize), nil
+}
+
+// TrailerBlock 扇区的区块尾块号
+func (g ClassicGeometry) TrailerBlock(sector int) (byte, error) {
+	first, err := g.FirstBlock(sector)
+	if err != nil {
+		return 0, err
+	}
+	return first + byte(g.BlocksInSector(sector)-1), nil
+}
+
+// Block 将扇区内的块偏移转换为块号
+func (g ClassicGeometry) Block(sector, block int) (byte, error) {
+	first, err := g.FirstBlock(sector)
+	if err != nil {
+		return 0, err
+	}
+	if block < 0 || block >= g.BlocksInSector(sector) {
+		return 0, fmt.Errorf("block %d out of range in sector %d", block, sector)
+	}
+	return first + byte(block), nil
+}
+
+// Sector 将块号转换为扇区与扇区内的块偏移
+func (g ClassicGeometry) Sector(blockNum byte) (sector, block int, err error) {
+	n := int(blockNum)
+	if n >= g.Blocks() {
+		return 0, 0, fmt.Errorf("block %#02X out of range", blockNum)
+	}
+	if n < smallSectorBlock {
+		return n / smallSectorSize, n % smallSectorSize, nil
+	}
+	n -= smallSectorBlock
+	return smallSectors + n/largeSectorSize, n % largeSectorSize, nil
+}
+
+// IsTrailer 块是否为区块尾
+func (g ClassicGeometry) IsTrailer(blockNum byte) bool {
+	sector, block, err := g.Sector(blockNum)
+	return err == nil && block == g.BlocksInSector(sector)-1
+}
+
+// SectorBlocks 扇区内所有块的块号 包括区块尾
+func (g ClassicGeometry) SectorBlocks(sector int) []byte {
+	first, err := g.FirstBlock(sector)
+	if err != nil {
+		return nil
+	}
+	blocks := make([]byte, g.BlocksInSector(sector))
+	for i := range blocks {
+		blocks[i] = first + byte(i)
+	}
+	return blocks
+}
+
+// ForEachBlock 按顺序遍历所有块 fn返回错误时停止遍历并返回该错误
+func (g ClassicGeometry) ForEachBlock(fn func(sector, block int, blockNum byte) error) error {
+	for sector := 0; sector < g.Sectors; sector++ {
+		for block, blockNum := range g.SectorBlocks(sector) {
+			if err := fn(sector, block, blockNum); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// MifareClassicReadSectorBlock 按扇区与扇区内的块偏移读取块 需要先通过认证
+func (p *Pn532) MifareClassicReadSectorBlock(g ClassicGeometry, sector, block int) ([]byte, error) {
+	blockNum, err := g.Block(sector, block)
+	if err != nil {
+		return nil, err
+	}
+	return p.MifareClassicReadBlock(blockNum)
+}
+
+// MifareClassicWriteSectorBlock 按扇区与扇区内的块偏移写入数据块 需要先通过认证
+// 区块尾需要通过 MifareClassicWriteTrailer 写入 扇区0的块0为厂商块 同样拒绝写入
+func (p *Pn532) MifareClassicWriteSectorBlock(g ClassicGeometry, sector, block int, data []byte) error {
+	blockNum, err := g.Block(sector, block)
+	if err != nil {
+		return err
+	}
+	if g.IsTrailer(blockNum) {
+		return fmt.Errorf("block %#02X is a sector trailer, use MifareClassicWriteTrailer", blockNum)
+	}
+	if blockNum == 0 {
+		return errors.New("block 0 is the manufacturer block")
+	}
+	if len(data) != 16 {
+		return errors.New("data length must be 16")
+	}
+	ok, err := p.MifareClassicWriteBlock(blockNum, data)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("write block failed")
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package pn532
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestClassicGeometry(t *testing.T) {
+	for _, tc := range []struct {
+		g      ClassicGeometry
+		blocks int
+	}{{ClassicMini, 20}, {Classic1K, 64}, {Classic2K, 128}, {Classic4K, 256}} {
+		if tc.g.Blocks() != tc.blocks {
+			t.Errorf("%d sectors: %d blocks", tc.g.Sectors, tc.g.Blocks())
+		}
+		n := 0
+		trailers := 0
+		err := tc.g.ForEachBlock(func(sector, block int, blockNum byte) error {
+			if int(blockNum) != n {
+				t.Errorf("block %d visited as %d", n, blockNum)
+			}
+			if s, b, err := tc.g.Sector(blockNum); err != nil || s != sector || b != block {
+				t.Errorf("block %d: sector %d/%d block %d/%d %v", blockNum, s, sector, b, block, err)
+			}
+			if tc.g.IsTrailer(blockNum) {
+				trailers++
+			}
+			n++
+			return nil
+		})
+		if err != nil || n != tc.blocks || trailers != tc.g.Sectors {
+			t.Errorf("%d sectors: visited %d blocks, %d trailers", tc.g.Sectors, n, trailers)
+		}
+	}
+
+	if b, err := Classic1K.Block(14, 2); err != nil || b != 0x3A {
+		t.Errorf("unexpected block: %#X %v", b, err)
+	}
+	if b, _ := Classic4K.TrailerBlock(32); b != 0x8F {
+		t.Errorf("unexpected trailer: %#X", b)
+	}
+	if b, _ := Classic4K.TrailerBlock(39); b != 0xFF {
+		t.Errorf("unexpected trailer: %#X", b)
+	}
+	if s, b, _ := Classic4K.Sector(0xC5); s != 36 || b != 5 {
+		t.Errorf("unexpected sector: %d %d", s, b)
+	}
+	if !bytes.Equal(Classic4K.SectorBlocks(33), []byte{
+		0x90, 0x91, 0x92, 0x93, 0x94, 0x95, 0x96, 0x97, 0x98, 0x99, 0x9A, 0x9B, 0x9C, 0x9D, 0x9E, 0x9F,
+	}) {
+		t.Error("unexpected sector blocks")
+	}
+	if _, err := Classic1K.Block(16, 0); err == nil {
+		t.Error("sector out of range accepted")
+	}
+	if _, err := Classic1K.Block(1, 4); err == nil {
+		t.Error("block out of range accepted")
+	}
+	if _, _, err := Classic1K.Sector(0x40); err == nil {
+		t.Error("block out of range accepted")
+	}
+	if Classic1K.IsTrailer(0x43) {
+		t.Error("out of range trailer")
+	}
+}
+
+func TestClassicGeometryFrom(t *testing.T) {
+	if g, err := ClassicGeometryFromSAK(0x18); err != nil || g != Classic4K {
+		t.Errorf("unexpected geometry: %v %v", g, err)
+	}
+	if _, err := ClassicGeometryFromSAK(0x20); err == nil {
+		t.Error("ISO14443-4 SAK accepted")
+	}
+	info, _ := identifyATQASAK(&TargetA{ATQA: [2]byte{0x00, 0x04}, SAK: 0x28})
+	if g, err := ClassicGeometryFromCardInfo(info); err != nil || g != Classic1K {
+		t.Errorf("unexpected geometry: %v %v", g, err)
+	}
+	if _, err := ClassicGeometryFromCardInfo(&CardInfo{Product: ProductNTAG213}); err == nil {
+		t.Error("NTAG accepted")
+	}
+}
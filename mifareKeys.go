@@ -0,0 +1,190 @@
+package pn532
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"github.com/asjdf/pn532/command"
+	"io"
+	"os"
+	"strings"
+)
+
+// ParseKeyDictionary 解析.dic/.keys密码字典 每行一个12位十六进制密码
+// #之后为注释 空行会被忽略 重复的密码只保留第一个
+func ParseKeyDictionary(r io.Reader) ([][]byte, error) {
+	var keys [][]byte
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		key, err := hex.DecodeString(line)
+		if err != nil || len(key) != 6 {
+			return nil, fmt.Errorf("line %d: invalid key %q", n, line)
+		}
+		keys = appendUniqueKey(keys, key)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func appendUniqueKey(keys [][]byte, key []byte) [][]byte {
+	for _, exist := range keys {
+		if bytes.Equal(exist, key) {
+			return keys
+		}
+	}
+	return append(keys, key)
+}
+
+// LoadKeyDictionary 读取并合并多个密码字典
+func LoadKeyDictionary(paths ...string) ([][]byte, error) {
+	var keys [][]byte
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		loaded, err := ParseKeyDictionary(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		for _, key := range loaded {
+			keys = appendUniqueKey(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// WriteKeyDictionary 按.dic格式写出密码
+func WriteKeyDictionary(w io.Writer, keys [][]byte) error {
+	for _, key := range keys {
+		if _, err := fmt.Fprintln(w, hexUpper(key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClassicKeyReport 密码检查的结果
+type ClassicKeyReport struct {
+	Geometry  ClassicGeometry
+	Sectors   []ClassicSectorKeys
+	Attempts  int // 认证次数
+	Reselects int // 认证失败后重新激活的次数
+}
+
+// Found 找到的密码数量 每个扇区最多两个
+func (r *ClassicKeyReport) Found() int {
+	n := 0
+	for _, s := range r.Sectors {
+		if s.KeyA != nil {
+			n++
+		}
+		if s.KeyB != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// Keys 找到的所有密码 去除重复
+func (r *ClassicKeyReport) Keys() [][]byte {
+	var keys [][]byte
+	for _, s := range r.Sectors {
+		for _, key := range [][]byte{s.KeyA, s.KeyB} {
+			if key != nil {
+				keys = appendUniqueKey(keys, key)
+			}
+		}
+	}
+	return keys
+}
+
+// KeySet 将结果转换为 DumpClassic 使用的密码
+func (r *ClassicKeyReport) KeySet() *ClassicKeySet {
+	set := &ClassicKeySet{Sectors: map[int][][]byte{}}
+	for i, s := range r.Sectors {
+		for _, key := range [][]byte{s.KeyA, s.KeyB} {
+			if key != nil {
+				set.Sectors[i] = appendUniqueKey(set.Sectors[i], key)
+			}
+		}
+	}
+	return set
+}
+
+// WriteKeys 按.dic格式写出找到的密码
+func (r *ClassicKeyReport) WriteKeys(w io.Writer) error {
+	return WriteKeyDictionary(w, r.Keys())
+}
+
+func (r *ClassicKeyReport) String() string {
+	var b strings.Builder
+	b.WriteString("sector  key A         key B\n")
+	for i, s := range r.Sectors {
+		keyA, keyB := "------------", "------------"
+		if s.KeyA != nil {
+			keyA = hexUpper(s.KeyA)
+		}
+		if s.KeyB != nil {
+			keyB = hexUpper(s.KeyB)
+		}
+		fmt.Fprintf(&b, "%6d  %s  %s\n", i, keyA, keyB)
+	}
+	fmt.Fprintf(&b, "found %d/%d keys, %d attempts, %d reselects\n", r.Found(), len(r.Sectors)*2, r.Attempts, r.Reselects)
+	return b.String()
+}
+
+// CheckClassicKeys 在所有扇区上检查字典中的密码A与密码B
+// 已经找到的密码会优先在其他扇区尝试 只有认证失败后才会重新激活卡片
+// t 必须是当前选择的目标 否则返回 ErrNoTarget
+func (p *Pn532) CheckClassicKeys(t *TargetA, keys [][]byte) (*ClassicKeyReport, error) {
+	if t == nil || p.current != Target(t) || t.State() != TargetSelected {
+		return nil, ErrNoTarget
+	}
+	g, err := ClassicGeometryFromSAK(t.SAK)
+	if err != nil {
+		return nil, err
+	}
+	report := &ClassicKeyReport{Geometry: g, Sectors: make([]ClassicSectorKeys, g.Sectors)}
+	var found [][]byte
+	for sector := 0; sector < g.Sectors; sector++ {
+		trailer, _ := g.TrailerBlock(sector)
+		candidates := append([][]byte(nil), found...)
+		for _, key := range keys {
+			candidates = appendUniqueKey(candidates, key)
+		}
+		for _, keyType := range []byte{command.MifareCmdAuthA, command.MifareCmdAuthB} {
+			for _, key := range candidates {
+				report.Attempts++
+				err := p.MifareClassicAuthenticate(t, trailer, keyType, key)
+				if err == nil {
+					if keyType == command.MifareCmdAuthA {
+						report.Sectors[sector].KeyA = key
+					} else {
+						report.Sectors[sector].KeyB = key
+					}
+					found = appendUniqueKey(found, key)
+					break
+				}
+				if !isStatusError(err) {
+					return report, err
+				}
+				// 认证失败后卡片进入HALT状态 MifareClassicAuthenticate 已经重新激活
+				report.Reselects++
+			}
+		}
+	}
+	return report, nil
+}
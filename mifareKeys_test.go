@@ -0,0 +1,75 @@
+package pn532
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseKeyDictionary(t *testing.T) {
+	dic := `# default keys
+FFFFFFFFFFFF
+a0a1a2a3a4a5  # MAD
+
+ffffffffffff
+D3F7D3F7D3F7
+`
+	keys, err := ParseKeyDictionary(strings.NewReader(dic))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 3 || !bytes.Equal(keys[1], []byte{0xA0, 0xA1, 0xA2, 0xA3, 0xA4, 0xA5}) {
+		t.Errorf("unexpected keys: % X", keys)
+	}
+	var b bytes.Buffer
+	if err := WriteKeyDictionary(&b, keys); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "FFFFFFFFFFFF\nA0A1A2A3A4A5\nD3F7D3F7D3F7\n" {
+		t.Errorf("unexpected output: %q", b.String())
+	}
+	if _, err := ParseKeyDictionary(strings.NewReader("FFFFFFFFFFFF\nFFFF\n")); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestClassicKeyReport(t *testing.T) {
+	keyA := []byte{0xA0, 0xA1, 0xA2, 0xA3, 0xA4, 0xA5}
+	keyB := []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+	r := &ClassicKeyReport{Geometry: ClassicMini, Sectors: []ClassicSectorKeys{
+		{KeyA: keyA, KeyB: keyB}, {KeyA: keyA}, {}, {KeyB: keyB}, {},
+	}}
+	if r.Found() != 4 || len(r.Keys()) != 2 {
+		t.Errorf("unexpected report: %d %d", r.Found(), len(r.Keys()))
+	}
+	if set := r.KeySet(); len(set.Sectors[0]) != 2 || len(set.Sectors[2]) != 0 {
+		t.Errorf("unexpected key set: %v", set.Sectors)
+	}
+	if s := r.String(); !strings.Contains(s, "     1  A0A1A2A3A4A5  ------------\n") {
+		t.Errorf("unexpected output:\n%s", s)
+	}
+}
+
+func TestCheckClassicKeys(t *testing.T) {
+	card := newFakeClassic()
+	card.keys[2] = [2][]byte{DefaultClassicKeys[1], DefaultClassicKeys[2]}
+	p, target := card.target()
+
+	// 不是当前选择的目标
+	other := &TargetA{targetBase: targetBase{tg: 0x02}, SAK: 0x09, UID: []byte{0x01, 0x02, 0x03, 0x04}}
+	if _, err := p.CheckClassicKeys(other, DefaultClassicKeys); err != ErrNoTarget {
+		t.Errorf("expect ErrNoTarget, got %v", err)
+	}
+
+	report, err := p.CheckClassicKeys(target, DefaultClassicKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Found() != 10 || !bytes.Equal(report.Sectors[2].KeyA, DefaultClassicKeys[1]) || !bytes.Equal(report.Sectors[2].KeyB, DefaultClassicKeys[2]) {
+		t.Errorf("unexpected report:\n%s", report)
+	}
+	// 扇区2的密码A失败1次 密码B失败2次
+	if report.Reselects != 3 || card.reactive != 3 {
+		t.Errorf("unexpected reselects: %d %d", report.Reselects, card.reactive)
+	}
+}
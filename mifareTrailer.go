@@ -0,0 +1,211 @@
+package pn532
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidAccessBits 访问控制位与其取反的副本不一致 写入后整个扇区将无法访问
+var ErrInvalidAccessBits = errors.New("invalid access bits")
+
+// ErrPermanentLock 写入后访问控制位将无法再修改
+var ErrPermanentLock = errors.New("access bits would be locked permanently")
+
+// AccessCondition 一个块的访问条件 按C1 C2 C3的顺序排列 例如C1=0 C2=0 C3=1为0b001
+type AccessCondition byte
+
+// 常用的访问条件
+const (
+	// AccessDataTransport 数据块出厂状态 密码A或B均可读写与增减值
+	AccessDataTransport AccessCondition = 0x00
+	// AccessTrailerTransport 区块尾出厂状态 密码A可以修改所有内容 密码B可读
+	AccessTrailerTransport AccessCondition = 0x01
+	// AccessTrailerKeyB 只有密码B可以修改密码与访问控制位
+	AccessTrailerKeyB AccessCondition = 0x03
+)
+
+func (c AccessCondition) c1() byte { return byte(c) >> 2 & 0x01 }
+func (c AccessCondition) c2() byte { return byte(c) >> 1 & 0x01 }
+func (c AccessCondition) c3() byte { return byte(c) & 0x01 }
+
+// 数据块访问条件的说明 依次为 读 写 增值 减值/传输/恢复
+var dataAccessTable = [8][4]string{
+	{"A|B", "A|B", "A|B", "A|B"},
+	{"A|B", "never", "never", "A|B"},
+	{"A|B", "never", "never", "never"},
+	{"B", "B", "never", "never"},
+	{"A|B", "B", "never", "never"},
+	{"B", "never", "never", "never"},
+	{"A|B", "B", "B", "A|B"},
+	{"never", "never", "never", "never"},
+}
+
+// 区块尾访问条件的说明 依次为 写密码A 读访问控制位 写访问控制位 读密码B 写密码B
+var trailerAccessTable = [8][5]string{
+	{"A", "A", "never", "A", "A"},
+	{"A", "A", "A", "A", "A"},
+	{"never", "A", "never", "A", "never"},
+	{"B", "A|B", "B", "never", "B"},
+	{"B", "A|B", "never", "never", "B"},
+	{"never", "A|B", "B", "never", "never"},
+	{"never", "A|B", "never", "never", "never"},
+	{"never", "A|B", "never", "never", "never"},
+}
+
+// DataString 作为数据块时的访问条件说明
+func (c AccessCondition) DataString() string {
+	a := dataAccessTable[c&0x07]
+	return fmt.Sprintf("read: %s, write: %s, increment: %s, decrement/transfer/restore: %s", a[0], a[1], a[2], a[3])
+}
+
+// TrailerString 作为区块尾时的访问条件说明
+func (c AccessCondition) TrailerString() string {
+	a := trailerAccessTable[c&0x07]
+	return fmt.Sprintf("key A write: %s, access bits read: %s, write: %s, key B read: %s, write: %s", a[0], a[1], a[2], a[3], a[4])
+}
+
+// AccessBitsWritable 作为区块尾时访问控制位是否还能被修改
+func (c AccessCondition) AccessBitsWritable() bool {
+	return trailerAccessTable[c&0x07][2] != "never"
+}
+
+// KeyBReadable 作为区块尾时密码B是否可读 可读时密码B不能用于认证 只能当作数据使用
+func (c AccessCondition) KeyBReadable() bool {
+	return trailerAccessTable[c&0x07][3] != "never"
+}
+
+// EncodeAccessBits 编码访问控制位 access[0]-[2]为数据块 access[3]为区块尾
+// 4K卡片的大扇区中 access[0]-[2]分别对应5个连续的数据块
+func EncodeAccessBits(access [4]AccessCondition) [3]byte {
+	var c1, c2, c3 byte
+	for i, c := range access {
+		c1 |= c.c1() << i
+		c2 |= c.c2() << i
+		c3 |= c.c3() << i
+	}
+	return [3]byte{
+		(^c2&0x0F)<<4 | ^c1&0x0F,
+		c1<<4 | ^c3&0x0F,
+		c3<<4 | c2,
+	}
+}
+
+// DecodeAccessBits 解析访问控制位 并校验取反的副本
+func DecodeAccessBits(b [3]byte) ([4]AccessCondition, error) {
+	var access [4]AccessCondition
+	c1, c2, c3 := b[1]>>4, b[2]&0x0F, b[2]>>4
+	if ^b[0]&0x0F != c1 || ^b[0]>>4 != c2 || ^b[1]&0x0F != c3 {
+		return access, ErrInvalidAccessBits
+	}
+	for i := range access {
+		access[i] = AccessCondition((c1>>i&0x01)<<2 | (c2>>i&0x01)<<1 | c3>>i&0x01)
+	}
+	return access, nil
+}
+
+// SectorTrailer 区块尾 每个扇区的最后一个块
+type SectorTrailer struct {
+	KeyA   [6]byte
+	Access [4]AccessCondition
+	GPB    byte // General Purpose Byte
+	KeyB   [6]byte
+}
+
+// DefaultSectorTrailer 出厂状态的区块尾 FF..FF FF 07 80 69 FF..FF
+func DefaultSectorTrailer() *SectorTrailer {
+	t := &SectorTrailer{
+		Access: [4]AccessCondition{AccessDataTransport, AccessDataTransport, AccessDataTransport, AccessTrailerTransport},
+		GPB:    0x69,
+	}
+	copy(t.KeyA[:], bytes.Repeat([]byte{0xFF}, 6))
+	copy(t.KeyB[:], bytes.Repeat([]byte{0xFF}, 6))
+	return t
+}
+
+// ParseSectorTrailer 解析区块尾 读取时密码A总是返回0 密码B不可读时同样为0
+func ParseSectorTrailer(data []byte) (*SectorTrailer, error) {
+	if len(data) != 16 {
+		return nil, errors.New("sector trailer length must be 16")
+	}
+	access, err := DecodeAccessBits([3]byte{data[6], data[7], data[8]})
+	if err != nil {
+		return nil, err
+	}
+	t := &SectorTrailer{Access: access, GPB: data[9]}
+	copy(t.KeyA[:], data[0:6])
+	copy(t.KeyB[:], data[10:16])
+	return t, nil
+}
+
+// Bytes 编码区块尾
+func (t *SectorTrailer) Bytes() []byte {
+	bits := EncodeAccessBits(t.Access)
+	data := make([]byte, 0, 16)
+	data = append(data, t.KeyA[:]...)
+	data = append(data, bits[:]...)
+	data = append(data, t.GPB)
+	return append(data, t.KeyB[:]...)
+}
+
+func (t *SectorTrailer) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "key A: % X, key B: % X, GPB: %02X\n", t.KeyA, t.KeyB, t.GPB)
+	for i, c := range t.Access[:3] {
+		fmt.Fprintf(&b, "block %d (%03b): %s\n", i, c, c.DataString())
+	}
+	fmt.Fprintf(&b, "trailer (%03b): %s\n", t.Access[3], t.Access[3].TrailerString())
+	return b.String()
+}
+
+// TrailerWriteOptions 写入区块尾时的检查选项
+type TrailerWriteOptions struct {
+	// AllowInvalidAccessBits 允许写入不一致的访问控制位 卡片会永久锁定整个扇区
+	AllowInvalidAccessBits bool
+	// AllowPermanentLock 允许写入之后无法再修改的访问控制位
+	AllowPermanentLock bool
+}
+
+// CheckSectorTrailer 检查区块尾数据是否可以安全写入
+func CheckSectorTrailer(data []byte, opt TrailerWriteOptions) error {
+	if len(data) != 16 {
+		return errors.New("sector trailer length must be 16")
+	}
+	t, err := ParseSectorTrailer(data)
+	if err != nil {
+		if opt.AllowInvalidAccessBits {
+			return nil
+		}
+		return err
+	}
+	if !t.Access[3].AccessBitsWritable() && !opt.AllowPermanentLock {
+		return ErrPermanentLock
+	}
+	return nil
+}
+
+// MifareClassicWriteTrailer 检查后写入区块尾 需要先通过认证
+// 默认拒绝写入不一致的访问控制位以及会永久锁定访问控制位的数据
+func (p *Pn532) MifareClassicWriteTrailer(blockNum byte, trailer *SectorTrailer, opt TrailerWriteOptions) error {
+	return p.MifareClassicWriteTrailerBytes(blockNum, trailer.Bytes(), opt)
+}
+
+// MifareClassicWriteTrailerBytes 检查后写入原始的区块尾数据 需要先通过认证
+// 较小的卡片与4K的前部布局相同 因此按4K的布局判断块号是否为区块尾
+func (p *Pn532) MifareClassicWriteTrailerBytes(blockNum byte, data []byte, opt TrailerWriteOptions) error {
+	if !Classic4K.IsTrailer(blockNum) {
+		return fmt.Errorf("block %#X is not a sector trailer", blockNum)
+	}
+	if err := CheckSectorTrailer(data, opt); err != nil {
+		return err
+	}
+	ok, err := p.MifareClassicWriteBlock(blockNum, data)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("write sector trailer failed")
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package pn532
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSectorTrailer(t *testing.T) {
+	transport := []byte{
+		0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
+		0xFF, 0x07, 0x80, 0x69,
+		0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
+	}
+	if data := DefaultSectorTrailer().Bytes(); !bytes.Equal(data, transport) {
+		t.Errorf("unexpected default trailer: % X", data)
+	}
+	trailer, err := ParseSectorTrailer(transport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trailer.Access != [4]AccessCondition{0, 0, 0, AccessTrailerTransport} {
+		t.Errorf("unexpected access: %v", trailer.Access)
+	}
+	if !trailer.Access[3].KeyBReadable() || !trailer.Access[3].AccessBitsWritable() {
+		t.Error("wrong transport trailer flags")
+	}
+
+	// 常见的 78 77 88 配置: 数据块 A读 B读写 区块尾由B管理
+	access := [4]AccessCondition{0x04, 0x04, 0x04, AccessTrailerKeyB}
+	if bits := EncodeAccessBits(access); bits != [3]byte{0x78, 0x77, 0x88} {
+		t.Errorf("unexpected access bits: % X", bits)
+	}
+	for c := AccessCondition(0); c < 8; c++ {
+		access := [4]AccessCondition{c, 7 - c, c, c}
+		decoded, err := DecodeAccessBits(EncodeAccessBits(access))
+		if err != nil || decoded != access {
+			t.Errorf("round trip %v: %v %v", access, decoded, err)
+		}
+	}
+	if _, err := DecodeAccessBits([3]byte{0xFF, 0x07, 0x81}); err != ErrInvalidAccessBits {
+		t.Error("inconsistent access bits accepted")
+	}
+}
+
+func TestCheckSectorTrailer(t *testing.T) {
+	trailer := DefaultSectorTrailer()
+	if err := CheckSectorTrailer(trailer.Bytes(), TrailerWriteOptions{}); err != nil {
+		t.Error(err)
+	}
+	trailer.Access[3] = 0x06
+	if err := CheckSectorTrailer(trailer.Bytes(), TrailerWriteOptions{}); err != ErrPermanentLock {
+		t.Errorf("permanent lock not refused: %v", err)
+	}
+	if err := CheckSectorTrailer(trailer.Bytes(), TrailerWriteOptions{AllowPermanentLock: true}); err != nil {
+		t.Error(err)
+	}
+	bad := DefaultSectorTrailer().Bytes()
+	bad[8] = 0x00
+	if err := CheckSectorTrailer(bad, TrailerWriteOptions{}); err != ErrInvalidAccessBits {
+		t.Errorf("invalid access bits not refused: %v", err)
+	}
+	if err := CheckSectorTrailer(bad, TrailerWriteOptions{AllowInvalidAccessBits: true}); err != nil {
+		t.Error(err)
+	}
+	if err := CheckSectorTrailer(bad[:15], TrailerWriteOptions{AllowInvalidAccessBits: true}); err == nil {
+		t.Error("short trailer accepted")
+	}
+}
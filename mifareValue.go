@@ -0,0 +1,128 @@
+package pn532
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/asjdf/pn532/command"
+)
+
+// ErrInvalidValueBlock 块数据不是合法的值块格式
+var ErrInvalidValueBlock = errors.New("invalid value block")
+
+// EncodeValueBlock 按值块格式编码 value ~value value addr ~addr addr ~addr
+// value 以小端存储 addr 通常为块号 可用于备份管理
+func EncodeValueBlock(value int32, addr byte) []byte {
+	block := make([]byte, 16)
+	v := uint32(value)
+	binary.LittleEndian.PutUint32(block[0:], v)
+	binary.LittleEndian.PutUint32(block[4:], ^v)
+	binary.LittleEndian.PutUint32(block[8:], v)
+	block[12], block[13], block[14], block[15] = addr, ^addr, addr, ^addr
+	return block
+}
+
+// DecodeValueBlock 解析并校验值块 返回值与地址字节
+func DecodeValueBlock(block []byte) (int32, byte, error) {
+	if len(block) != 16 {
+		return 0, 0, ErrInvalidValueBlock
+	}
+	v := binary.LittleEndian.Uint32(block[0:])
+	if binary.LittleEndian.Uint32(block[4:]) != ^v || binary.LittleEndian.Uint32(block[8:]) != v {
+		return 0, 0, ErrInvalidValueBlock
+	}
+	addr := block[12]
+	if block[13] != ^addr || block[14] != addr || block[15] != ^addr {
+		return 0, 0, ErrInvalidValueBlock
+	}
+	return int32(v), addr, nil
+}
+
+// MifareClassicWriteValue 将块格式化为值块 需要先通过认证
+func (p *Pn532) MifareClassicWriteValue(blockNum byte, value int32, addr byte) error {
+	_, err := p.InDataExchange(append([]byte{command.MifareCmdWrite, blockNum}, EncodeValueBlock(value, addr)...))
+	return err
+}
+
+// MifareClassicReadValue 读取并校验值块 返回值与地址字节
+func (p *Pn532) MifareClassicReadValue(blockNum byte) (int32, byte, error) {
+	data, err := p.InDataExchange([]byte{command.MifareCmdRead, blockNum})
+	if err != nil {
+		return 0, 0, err
+	}
+	return DecodeValueBlock(data)
+}
+
+// valueCommand 发送增值/减值/恢复命令 结果保存在卡片的传输缓冲区中 需要通过Transfer写入
+func (p *Pn532) valueCommand(cmd, blockNum byte, operand uint32) error {
+	data := []byte{cmd, blockNum, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(data[2:], operand)
+	_, err := p.InDataExchange(data)
+	return err
+}
+
+// MifareClassicIncrement 将值块的值加上delta 结果保存在传输缓冲区中
+func (p *Pn532) MifareClassicIncrement(blockNum byte, delta uint32) error {
+	return p.valueCommand(command.MifareCmdIncrement, blockNum, delta)
+}
+
+// MifareClassicDecrement 将值块的值减去delta 结果保存在传输缓冲区中
+func (p *Pn532) MifareClassicDecrement(blockNum byte, delta uint32) error {
+	return p.valueCommand(command.MifareCmdDecrement, blockNum, delta)
+}
+
+// MifareClassicRestore 将值块的值读入传输缓冲区 配合Transfer可以复制值块
+func (p *Pn532) MifareClassicRestore(blockNum byte) error {
+	return p.valueCommand(command.MifareCmdStore, blockNum, 0)
+}
+
+// MifareClassicTransfer 将传输缓冲区的值写入块
+func (p *Pn532) MifareClassicTransfer(blockNum byte) error {
+	_, err := p.InDataExchange([]byte{command.MifareCmdTransfer, blockNum})
+	return err
+}
+
+// MifareClassicIncrementValue 增值并写回同一块 写入后读回校验 返回新的值
+func (p *Pn532) MifareClassicIncrementValue(blockNum byte, delta uint32) (int32, error) {
+	return p.valueOperation(command.MifareCmdIncrement, blockNum, blockNum, delta)
+}
+
+// MifareClassicDecrementValue 减值并写回同一块 写入后读回校验 返回新的值
+func (p *Pn532) MifareClassicDecrementValue(blockNum byte, delta uint32) (int32, error) {
+	return p.valueOperation(command.MifareCmdDecrement, blockNum, blockNum, delta)
+}
+
+// MifareClassicCopyValue 通过Restore与Transfer将src的值复制到同一扇区的dst 写入后读回校验
+func (p *Pn532) MifareClassicCopyValue(src, dst byte) (int32, error) {
+	return p.valueOperation(command.MifareCmdStore, src, dst, 0)
+}
+
+// valueOperation 读取原值 执行值操作与Transfer 再读回dst确认结果
+// 原值不是合法的值块时不会进行任何写入
+func (p *Pn532) valueOperation(cmd, src, dst byte, operand uint32) (int32, error) {
+	value, _, err := p.MifareClassicReadValue(src)
+	if err != nil {
+		return 0, err
+	}
+	expected := value
+	switch cmd {
+	case command.MifareCmdIncrement:
+		expected += int32(operand)
+	case command.MifareCmdDecrement:
+		expected -= int32(operand)
+	}
+	if err := p.valueCommand(cmd, src, operand); err != nil {
+		return 0, err
+	}
+	if err := p.MifareClassicTransfer(dst); err != nil {
+		return 0, err
+	}
+	got, _, err := p.MifareClassicReadValue(dst)
+	if err != nil {
+		return 0, err
+	}
+	if got != expected {
+		return got, fmt.Errorf("value verification failed: expected %d, got %d", expected, got)
+	}
+	return got, nil
+}
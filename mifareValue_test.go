@@ -0,0 +1,33 @@
+package pn532
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestValueBlock(t *testing.T) {
+	block := EncodeValueBlock(100, 0x05)
+	want := []byte{
+		0x64, 0x00, 0x00, 0x00, 0x9B, 0xFF, 0xFF, 0xFF,
+		0x64, 0x00, 0x00, 0x00, 0x05, 0xFA, 0x05, 0xFA,
+	}
+	if !bytes.Equal(block, want) {
+		t.Errorf("unexpected block: % X", block)
+	}
+	for _, v := range []int32{0, 1, -1, 2147483647, -2147483648} {
+		value, addr, err := DecodeValueBlock(EncodeValueBlock(v, 0x3A))
+		if err != nil || value != v || addr != 0x3A {
+			t.Errorf("round trip %d: %d %#X %v", v, value, addr, err)
+		}
+	}
+	for i := range block {
+		bad := append([]byte(nil), block...)
+		bad[i] ^= 0x01
+		if _, _, err := DecodeValueBlock(bad); err != ErrInvalidValueBlock {
+			t.Errorf("corrupted byte %d accepted", i)
+		}
+	}
+	if _, _, err := DecodeValueBlock(block[:15]); err != ErrInvalidValueBlock {
+		t.Error("short block accepted")
+	}
+}
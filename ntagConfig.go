@@ -0,0 +1,346 @@
+package pn532
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNoConfigPages 卡片没有NTAG21x / Ultralight EV1 的配置页
+	ErrNoConfigPages = errors.New("tag has no configuration pages")
+	// ErrConfigLocked CFGLCK已置位 CFG0与CFG1无法再修改
+	ErrConfigLocked = errors.New("configuration is locked")
+	// ErrIrreversibleLock 写入会置位无法清除的锁定位或CFGLCK
+	ErrIrreversibleLock = errors.New("lock bits would be set permanently")
+)
+
+// NTAGMirror ASCII镜像的内容 镜像会在读取时把UID或NFC计数器以ASCII形式替换到用户区
+type NTAGMirror byte
+
+const (
+	MirrorNone       NTAGMirror = 0x00
+	MirrorUID        NTAGMirror = 0x01 // 14字节 UID的十六进制
+	MirrorCounter    NTAGMirror = 0x02 // 6字节 NFC计数器的十六进制
+	MirrorUIDCounter NTAGMirror = 0x03 // 21字节 UID x 计数器
+)
+
+// length 镜像占用的字节数
+func (m NTAGMirror) length() int {
+	switch m {
+	case MirrorUID:
+		return 14
+	case MirrorCounter:
+		return 6
+	case MirrorUIDCounter:
+		return 21
+	}
+	return 0
+}
+
+func (m NTAGMirror) String() string {
+	switch m {
+	case MirrorNone:
+		return "none"
+	case MirrorUID:
+		return "UID"
+	case MirrorCounter:
+		return "NFC counter"
+	case MirrorUIDCounter:
+		return "UID and NFC counter"
+	}
+	return fmt.Sprintf("NTAGMirror(%#X)", byte(m))
+}
+
+// NTAGConfig NTAG21x / Ultralight EV1 的配置页与锁定字节
+// 配置区依次为CFG0 CFG1 PWD PACK四页 PWD与PACK只能写入 读取时总是返回0
+type NTAGConfig struct {
+	Mirror           NTAGMirror // 只有NTAG21x支持
+	MirrorByte       byte       // 镜像在MirrorPage中的起始字节 0-3
+	MirrorPage       byte       // 镜像的起始页 小于4时不启用镜像
+	StrongModulation bool
+	AUTH0            byte // 从此页开始需要密码 大于配置区最后一页时不启用密码保护
+	Prot             bool // true: 读写都需要密码 false: 只有写入需要密码
+	CfgLock          bool // 永久锁定CFG0与CFG1
+	AuthLimit        byte // 连续认证失败的次数限制为2^AuthLimit 0表示不限制 0-7
+	NFCCounter       bool // 启用NFC计数器 只有NTAG21x支持
+	NFCCounterProt   bool // 读取NFC计数器需要密码 只有NTAG21x支持
+
+	PWD  []byte // 4字节 读取时为nil 为nil时写回不修改
+	PACK []byte // 2字节 读取时为nil 为nil时写回不修改
+
+	StaticLock  [2]byte // 第2页的静态锁定字节
+	DynamicLock []byte  // 3字节的动态锁定字节 没有动态锁定页的卡片为nil
+
+	ntag bool
+	raw  [8]byte // 读取到的CFG0与CFG1 用于保留RFU位
+}
+
+// NTAGConfigOptions 写入配置的选项
+type NTAGConfigOptions struct {
+	// AllowPermanentLock 允许置位锁定位与CFGLCK 这些位置位后无法清除
+	AllowPermanentLock bool
+}
+
+// ConfigPage 配置区第一页(CFG0)的页号
+func (u *Ultralight) ConfigPage() (byte, error) {
+	if u.Version == nil || u.Version.VendorID != 0x04 {
+		return 0, ErrNoConfigPages
+	}
+	switch u.Version.ProductType {
+	case 0x03, 0x04: // Ultralight EV1, NTAG21x
+		return byte(u.Pages - 4), nil
+	}
+	return 0, ErrNoConfigPages
+}
+
+// dynamicLockPage 动态锁定页位于配置区之前 MF0UL11没有动态锁定页
+func (u *Ultralight) dynamicLockPage(cfgPage byte) (byte, bool) {
+	if u.Version.ProductType == 0x03 && u.Pages <= 20 {
+		return 0, false
+	}
+	return cfgPage - 1, true
+}
+
+func (u *Ultralight) isNTAG() bool {
+	return u.Version != nil && u.Version.ProductType == 0x04
+}
+
+// ReadConfig 读取配置页与锁定字节 PROT置位且配置页受保护时需要先调用PwdAuth
+func (u *Ultralight) ReadConfig() (*NTAGConfig, error) {
+	cfgPage, err := u.ConfigPage()
+	if err != nil {
+		return nil, err
+	}
+	data, err := u.Read(cfgPage)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &NTAGConfig{ntag: u.isNTAG()}
+	copy(cfg.raw[:], data[:8])
+	cfg.decode()
+
+	header, err := u.Read(0)
+	if err != nil {
+		return nil, err
+	}
+	copy(cfg.StaticLock[:], header[10:12])
+	if lockPage, ok := u.dynamicLockPage(cfgPage); ok {
+		data, err := u.Read(lockPage)
+		if err != nil {
+			return nil, err
+		}
+		cfg.DynamicLock = append([]byte(nil), data[:3]...)
+	}
+	return cfg, nil
+}
+
+// decode 从raw解析各字段
+func (c *NTAGConfig) decode() {
+	mirror, access := c.raw[0], c.raw[4]
+	if c.ntag {
+		c.Mirror = NTAGMirror(mirror >> 6)
+		c.MirrorByte = mirror >> 4 & 0x03
+		c.StrongModulation = mirror&0x04 != 0
+		c.NFCCounter = access&0x10 != 0
+		c.NFCCounterProt = access&0x08 != 0
+	} else {
+		c.StrongModulation = mirror&0x08 != 0
+	}
+	c.MirrorPage = c.raw[2]
+	c.AUTH0 = c.raw[3]
+	c.Prot = access&0x80 != 0
+	c.CfgLock = access&0x40 != 0
+	c.AuthLimit = access & 0x07
+}
+
+// pages 编码CFG0与CFG1 未建模的位保持读取时的值
+func (c *NTAGConfig) pages() (cfg0, cfg1 [4]byte) {
+	copy(cfg0[:], c.raw[:4])
+	copy(cfg1[:], c.raw[4:])
+	if c.ntag {
+		cfg0[0] &^= 0xF4
+		cfg0[0] |= byte(c.Mirror)<<6 | c.MirrorByte<<4
+		if c.StrongModulation {
+			cfg0[0] |= 0x04
+		}
+		cfg0[2] = c.MirrorPage
+	} else {
+		cfg0[0] &^= 0x08
+		if c.StrongModulation {
+			cfg0[0] |= 0x08
+		}
+	}
+	cfg0[3] = c.AUTH0
+
+	cfg1[0] &^= 0xC7
+	if c.ntag {
+		cfg1[0] &^= 0x18
+		if c.NFCCounter {
+			cfg1[0] |= 0x10
+		}
+		if c.NFCCounterProt {
+			cfg1[0] |= 0x08
+		}
+	}
+	if c.Prot {
+		cfg1[0] |= 0x80
+	}
+	if c.CfgLock {
+		cfg1[0] |= 0x40
+	}
+	cfg1[0] |= c.AuthLimit
+	return
+}
+
+// validate 检查字段的取值范围 cfgPage为CFG0的页号
+func (c *NTAGConfig) validate(cfgPage byte) error {
+	if c.AuthLimit > 7 {
+		return errors.New("AUTHLIM must be 0-7")
+	}
+	if c.PWD != nil && len(c.PWD) != 4 {
+		return errors.New("password length must be 4")
+	}
+	if c.PACK != nil && len(c.PACK) != 2 {
+		return errors.New("PACK length must be 2")
+	}
+	if !c.ntag {
+		if c.Mirror != MirrorNone || c.NFCCounter || c.NFCCounterProt {
+			return errors.New("mirror and NFC counter are only supported by NTAG21x")
+		}
+		return nil
+	}
+	if c.Mirror > MirrorUIDCounter || c.MirrorByte > 3 {
+		return errors.New("invalid mirror configuration")
+	}
+	if c.Mirror != MirrorNone && c.MirrorPage >= 4 {
+		// 镜像必须完整地落在用户区内 用户区在动态锁定页之前结束
+		end := int(c.MirrorPage)*4 + int(c.MirrorByte) + c.Mirror.length()
+		if end > int(cfgPage-1)*4 {
+			return errors.New("mirror exceeds user memory")
+		}
+	}
+	return nil
+}
+
+// lockChange 比较锁定字节 返回是否会置位新的锁定位 锁定位无法清除
+func lockChange(cur, want []byte) (bool, error) {
+	if len(cur) != len(want) {
+		return false, errors.New("lock bytes length mismatch")
+	}
+	set := false
+	for i := range cur {
+		if cur[i]&^want[i] != 0 {
+			return false, errors.New("lock bits cannot be cleared")
+		}
+		if want[i]&^cur[i] != 0 {
+			set = true
+		}
+	}
+	return set, nil
+}
+
+// WriteConfig 写回修改后的配置 只写入发生变化的页
+// 写入顺序为PWD PACK 锁定字节 CFG1 CFG0(AUTH0) 最后才置位CFGLCK
+// 这样在AUTH0生效之前密码已经写入 不会因为密码未知而锁死卡片
+// 置位锁定位或CFGLCK是不可逆的 需要 NTAGConfigOptions.AllowPermanentLock
+func (u *Ultralight) WriteConfig(cfg *NTAGConfig, opt *NTAGConfigOptions) error {
+	if opt == nil {
+		opt = &NTAGConfigOptions{}
+	}
+	cfgPage, err := u.ConfigPage()
+	if err != nil {
+		return err
+	}
+	cur, err := u.ReadConfig()
+	if err != nil {
+		return err
+	}
+	want := *cfg
+	want.ntag, want.raw = cur.ntag, cur.raw
+	if err := want.validate(cfgPage); err != nil {
+		return err
+	}
+
+	curCfg0, curCfg1 := cur.pages()
+	cfg0, cfg1 := want.pages()
+	if cur.CfgLock && (cfg0 != curCfg0 || cfg1 != curCfg1) {
+		return ErrConfigLocked
+	}
+	staticSet, err := lockChange(cur.StaticLock[:], want.StaticLock[:])
+	if err != nil {
+		return err
+	}
+	dynamicSet, err := lockChange(cur.DynamicLock, want.DynamicLock)
+	if err != nil {
+		return err
+	}
+	if (staticSet || dynamicSet || want.CfgLock && !cur.CfgLock) && !opt.AllowPermanentLock {
+		return ErrIrreversibleLock
+	}
+
+	if want.PWD != nil {
+		if err := u.Write(cfgPage+2, want.PWD); err != nil {
+			return err
+		}
+	}
+	if want.PACK != nil {
+		if err := u.Write(cfgPage+3, []byte{want.PACK[0], want.PACK[1], 0x00, 0x00}); err != nil {
+			return err
+		}
+	}
+	if dynamicSet {
+		lockPage, _ := u.dynamicLockPage(cfgPage)
+		if err := u.Write(lockPage, append(append([]byte(nil), want.DynamicLock...), 0x00)); err != nil {
+			return err
+		}
+	}
+	if staticSet {
+		// 第2页的前两个字节在写入时会被忽略 锁定字节按位或写入
+		if err := u.Write(2, []byte{0x00, 0x00, want.StaticLock[0], want.StaticLock[1]}); err != nil {
+			return err
+		}
+	}
+
+	// CFGLCK最后写入 否则CFG0将无法修改
+	lockedCfg1 := cfg1
+	cfg1[0] = cfg1[0]&^0x40 | curCfg1[0]&0x40
+	if cfg1 != curCfg1 {
+		if err := u.Write(cfgPage+1, cfg1[:]); err != nil {
+			return err
+		}
+	}
+	if cfg0 != curCfg0 {
+		if err := u.Write(cfgPage, cfg0[:]); err != nil {
+			return err
+		}
+	}
+	if lockedCfg1 != cfg1 {
+		// AUTH0刚刚覆盖了配置区 需要用新密码认证后才能继续写入
+		if !u.authenticated && want.AUTH0 <= cfgPage+1 && want.PWD != nil {
+			if _, err := u.PwdAuth(want.PWD, want.PACK); err != nil {
+				return err
+			}
+		}
+		if err := u.Write(cfgPage+1, lockedCfg1[:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// String 以可读形式输出配置
+func (c *NTAGConfig) String() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "AUTH0: 0x%02X, PROT: %t, CFGLCK: %t, AUTHLIM: %d\n", c.AUTH0, c.Prot, c.CfgLock, c.AuthLimit)
+	if c.ntag {
+		fmt.Fprintf(&b, "Mirror: %s at page 0x%02X byte %d, NFC counter: %t (protected: %t)\n",
+			c.Mirror, c.MirrorPage, c.MirrorByte, c.NFCCounter, c.NFCCounterProt)
+	}
+	fmt.Fprintf(&b, "Strong modulation: %t\n", c.StrongModulation)
+	fmt.Fprintf(&b, "Static lock: % X", c.StaticLock[:])
+	if c.DynamicLock != nil {
+		fmt.Fprintf(&b, ", dynamic lock: % X", c.DynamicLock)
+	}
+	return b.String()
+}
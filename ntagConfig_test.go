@@ -0,0 +1,131 @@
+package pn532
+
+import (
+	"bytes"
+	"testing"
+)
+
+// newConfiguredNTAG NTAG213的出厂配置
+func newConfiguredNTAG(t *testing.T) (*fakeNTAG, *Ultralight) {
+	tag := newFakeNTAG()
+	copy(tag.mem[0x28*4:], []byte{0x00, 0x00, 0x00, 0xBD, 0x04, 0x00, 0x00, 0xFF, 0x00, 0x05, 0x00, 0x00})
+	copy(tag.mem[0x2B*4:], make([]byte, 8))
+	u := tag.ultralight()
+	if err := u.detect(); err != nil {
+		t.Fatal(err)
+	}
+	return tag, u
+}
+
+func TestNTAGConfigRead(t *testing.T) {
+	tag, u := newConfiguredNTAG(t)
+	if page, err := u.ConfigPage(); err != nil || page != 0x29 {
+		t.Fatalf("unexpected config page: %#X %v", page, err)
+	}
+	tag.mem[0x29*4] = 0x54 // UID镜像 第1字节 强调制
+	tag.mem[0x29*4+2] = 0x10
+	tag.mem[0x2A*4] = 0x9B // PROT NFC计数器 计数器保护 AUTHLIM=3
+	cfg, err := u.ReadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Mirror != MirrorUID || cfg.MirrorByte != 1 || cfg.MirrorPage != 0x10 || !cfg.StrongModulation {
+		t.Errorf("unexpected mirror: %+v", cfg)
+	}
+	if cfg.AUTH0 != 0xFF || !cfg.Prot || cfg.CfgLock || cfg.AuthLimit != 3 || !cfg.NFCCounter || !cfg.NFCCounterProt {
+		t.Errorf("unexpected access: %+v", cfg)
+	}
+	if !bytes.Equal(cfg.DynamicLock, []byte{0x00, 0x00, 0x00}) || cfg.PWD != nil {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+	cfg0, cfg1 := cfg.pages()
+	if !bytes.Equal(append(cfg0[:], cfg1[:]...), tag.mem[0x29*4:0x2B*4]) {
+		t.Errorf("config does not round trip: % X % X", cfg0, cfg1)
+	}
+}
+
+func TestNTAGConfigWrite(t *testing.T) {
+	tag, u := newConfiguredNTAG(t)
+	cfg, err := u.ReadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.PWD = []byte{0x12, 0x34, 0x56, 0x78}
+	cfg.PACK = []byte{0xAB, 0xCD}
+	cfg.AUTH0 = 0x04
+	cfg.Prot = true
+	cfg.AuthLimit = 2
+	if err := u.WriteConfig(cfg, nil); err != nil {
+		t.Fatal(err)
+	}
+	// 密码必须在AUTH0之前写入
+	if !bytes.Equal(tag.writes, []byte{0x2B, 0x2C, 0x2A, 0x29}) {
+		t.Errorf("unexpected write order: % X", tag.writes)
+	}
+	if !bytes.Equal(tag.mem[0x29*4:0x2D*4], mustHex("040000048205000012345678ABCD0000")) {
+		t.Errorf("unexpected config pages: % X", tag.mem[0x29*4:0x2D*4])
+	}
+
+	// 没有变化时不写入
+	tag.writes = nil
+	cfg.PWD, cfg.PACK = nil, nil
+	if err := u.WriteConfig(cfg, nil); err != nil || len(tag.writes) != 0 {
+		t.Errorf("unexpected writes: % X %v", tag.writes, err)
+	}
+}
+
+func TestNTAGConfigLocks(t *testing.T) {
+	tag, u := newConfiguredNTAG(t)
+	cfg, err := u.ReadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.StaticLock[0] = 0x08
+	cfg.DynamicLock[0] = 0x01
+	cfg.CfgLock = true
+	if err := u.WriteConfig(cfg, nil); err != ErrIrreversibleLock || len(tag.writes) != 0 {
+		t.Fatalf("irreversible lock accepted: %v % X", err, tag.writes)
+	}
+	if err := u.WriteConfig(cfg, &NTAGConfigOptions{AllowPermanentLock: true}); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(tag.writes, []byte{0x28, 0x02, 0x2A}) {
+		t.Errorf("unexpected write order: % X", tag.writes)
+	}
+	if tag.mem[10] != 0x08 || tag.mem[0x28*4] != 0x01 || tag.mem[0x2A*4] != 0x40 {
+		t.Errorf("locks not written: % X", tag.mem)
+	}
+
+	cfg, err = u.ReadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.AUTH0 = 0x10
+	if err := u.WriteConfig(cfg, nil); err != ErrConfigLocked {
+		t.Errorf("locked config modified: %v", err)
+	}
+	cfg.AUTH0 = 0xFF
+	cfg.StaticLock[0] = 0x00
+	if err := u.WriteConfig(cfg, nil); err == nil {
+		t.Error("lock bits cleared")
+	}
+}
+
+func TestNTAGConfigValidate(t *testing.T) {
+	cfg := &NTAGConfig{ntag: true, Mirror: MirrorUIDCounter, MirrorPage: 0x23, MirrorByte: 3}
+	if err := cfg.validate(0x29); err == nil {
+		t.Error("mirror beyond user memory accepted")
+	}
+	cfg.MirrorPage = 0x20
+	if err := cfg.validate(0x29); err != nil {
+		t.Error(err)
+	}
+	cfg.AuthLimit = 8
+	if err := cfg.validate(0x29); err == nil {
+		t.Error("invalid AUTHLIM accepted")
+	}
+	cfg = &NTAGConfig{NFCCounter: true}
+	if err := cfg.validate(0x10); err == nil {
+		t.Error("NFC counter accepted for Ultralight EV1")
+	}
+}
@@ -0,0 +1,326 @@
+package pn532
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// ecCurve 短Weierstrass曲线 y^2 = x^3 + ax + b (mod p) 只用于验证签名
+type ecCurve struct {
+	p, a, b, n *big.Int
+	g          *ecPoint
+	size       int // 坐标的字节数
+}
+
+// ecPoint 仿射坐标的点 nil表示无穷远点
+type ecPoint struct {
+	x, y *big.Int
+}
+
+func hexInt(s string) *big.Int {
+	v, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("invalid hex integer: " + s)
+	}
+	return v
+}
+
+var (
+	// secp128r1 NTAG21x Ultralight EV1 NTAG I2C 的原厂签名曲线
+	secp128r1 = &ecCurve{
+		p:    hexInt("FFFFFFFDFFFFFFFFFFFFFFFFFFFFFFFF"),
+		a:    hexInt("FFFFFFFDFFFFFFFFFFFFFFFFFFFFFFFC"),
+		b:    hexInt("E87579C11079F43DD824993C2CEE5ED3"),
+		n:    hexInt("FFFFFFFE0000000075A30D1B9038A115"),
+		g:    &ecPoint{hexInt("161FF7528B899B2D0C28607CA52C5B86"), hexInt("CF5AC8395BAFEB13C02DA292DDED7A83")},
+		size: 16,
+	}
+	// secp224r1 DESFire EV2/EV3 的原厂签名曲线 即NIST P-224
+	secp224r1 = curveFromParams(elliptic.P224().Params())
+)
+
+// ecCurves 按名称查找曲线
+var ecCurves = map[string]*ecCurve{
+	"secp128r1": secp128r1,
+	"secp224r1": secp224r1,
+}
+
+// curveFromParams 转换a = -3的标准曲线参数
+func curveFromParams(params *elliptic.CurveParams) *ecCurve {
+	return &ecCurve{
+		p:    params.P,
+		a:    new(big.Int).Sub(params.P, big.NewInt(3)),
+		b:    params.B,
+		n:    params.N,
+		g:    &ecPoint{params.Gx, params.Gy},
+		size: (params.BitSize + 7) / 8,
+	}
+}
+
+func (c *ecCurve) onCurve(pt *ecPoint) bool {
+	if pt.x.Sign() < 0 || pt.x.Cmp(c.p) >= 0 || pt.y.Sign() < 0 || pt.y.Cmp(c.p) >= 0 {
+		return false
+	}
+	left := new(big.Int).Mul(pt.y, pt.y)
+	left.Mod(left, c.p)
+	right := new(big.Int).Mul(pt.x, pt.x)
+	right.Add(right, c.a)
+	right.Mul(right, pt.x)
+	right.Add(right, c.b)
+	right.Mod(right, c.p)
+	return left.Cmp(right) == 0
+}
+
+// slope 由分子分母计算斜率 (num / den) mod p
+func (c *ecCurve) slope(num, den *big.Int) *big.Int {
+	inv := new(big.Int).ModInverse(new(big.Int).Mod(den, c.p), c.p)
+	l := new(big.Int).Mul(num, inv)
+	return l.Mod(l, c.p)
+}
+
+// withSlope 已知斜率时计算P1 + P2
+func (c *ecCurve) withSlope(l *big.Int, p1, p2 *ecPoint) *ecPoint {
+	x := new(big.Int).Mul(l, l)
+	x.Sub(x, p1.x)
+	x.Sub(x, p2.x)
+	x.Mod(x, c.p)
+	y := new(big.Int).Sub(p1.x, x)
+	y.Mul(y, l)
+	y.Sub(y, p1.y)
+	y.Mod(y, c.p)
+	return &ecPoint{x, y}
+}
+
+func (c *ecCurve) double(pt *ecPoint) *ecPoint {
+	if pt == nil || pt.y.Sign() == 0 {
+		return nil
+	}
+	num := new(big.Int).Mul(pt.x, pt.x)
+	num.Mul(num, big.NewInt(3))
+	num.Add(num, c.a)
+	return c.withSlope(c.slope(num, new(big.Int).Lsh(pt.y, 1)), pt, pt)
+}
+
+func (c *ecCurve) add(p1, p2 *ecPoint) *ecPoint {
+	if p1 == nil {
+		return p2
+	}
+	if p2 == nil {
+		return p1
+	}
+	if p1.x.Cmp(p2.x) == 0 {
+		if p1.y.Cmp(p2.y) == 0 {
+			return c.double(p1)
+		}
+		return nil
+	}
+	num := new(big.Int).Sub(p2.y, p1.y)
+	den := new(big.Int).Sub(p2.x, p1.x)
+	return c.withSlope(c.slope(num, den), p1, p2)
+}
+
+func (c *ecCurve) scalarMult(pt *ecPoint, k *big.Int) *ecPoint {
+	var r *ecPoint
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		r = c.double(r)
+		if k.Bit(i) == 1 {
+			r = c.add(r, pt)
+		}
+	}
+	return r
+}
+
+// parsePoint 解析未压缩的公钥 04 || X || Y
+func (c *ecCurve) parsePoint(key []byte) (*ecPoint, error) {
+	if len(key) != 1+2*c.size || key[0] != 0x04 {
+		return nil, errors.New("public key must be an uncompressed point")
+	}
+	pt := &ecPoint{new(big.Int).SetBytes(key[1 : 1+c.size]), new(big.Int).SetBytes(key[1+c.size:])}
+	if !c.onCurve(pt) {
+		return nil, errors.New("public key is not on the curve")
+	}
+	return pt, nil
+}
+
+// verify ECDSA验证 NXP的原厂签名直接对UID签名 不经过哈希
+func (c *ecCurve) verify(pub *ecPoint, msg []byte, r, s *big.Int) bool {
+	if r.Sign() <= 0 || s.Sign() <= 0 || r.Cmp(c.n) >= 0 || s.Cmp(c.n) >= 0 {
+		return false
+	}
+	e := new(big.Int).SetBytes(msg)
+	if excess := len(msg)*8 - c.n.BitLen(); excess > 0 {
+		e.Rsh(e, uint(excess))
+	}
+	w := new(big.Int).ModInverse(s, c.n)
+	u1 := new(big.Int).Mul(e, w)
+	u1.Mod(u1, c.n)
+	u2 := new(big.Int).Mul(r, w)
+	u2.Mod(u2, c.n)
+	pt := c.add(c.scalarMult(c.g, u1), c.scalarMult(pub, u2))
+	if pt == nil {
+		return false
+	}
+	v := new(big.Int).Mod(pt.x, c.n)
+	return v.Cmp(r) == 0
+}
+
+// OriginalityKey NXP公布的原厂签名公钥
+type OriginalityKey struct {
+	Name      string
+	Curve     string // secp128r1 或 secp224r1
+	PublicKey []byte // 未压缩格式 04 || X || Y
+}
+
+// OriginalityKeys 各型号的原厂签名公钥 同一型号可能有多个批次的公钥 可以按需添加
+var OriginalityKeys = map[Product][]OriginalityKey{
+	ProductMifareUltralightEV1: {
+		{"NXP MIFARE Ultralight EV1", "secp128r1", mustDecodeHex("0490933BDCD6E99B4E255E3DA55389A827564E11718E017292FAF23226A96614B8")},
+	},
+	ProductNTAG213: {ntag21xOriginalityKey},
+	ProductNTAG215: {ntag21xOriginalityKey},
+	ProductNTAG216: {ntag21xOriginalityKey},
+	ProductNTAGI2C: {
+		{"NXP NTAG I2C", "secp128r1", mustDecodeHex("04A748B6A632FBEE2C0897702B33BEA1C074998E17B84ACA04FF267E5D2C91F6DC")},
+		{"NXP NTAG I2C plus", "secp128r1", mustDecodeHex("044F6D3F294DEA5737F0F46FFEE88A356EED95695DD7E0C27A591E6F6F65962BAF")},
+	},
+	ProductMifareDESFireEV2: {
+		{"NXP MIFARE DESFire EV2", "secp224r1", mustDecodeHex("04B304DC4C615F5326FE9383DDEC9AA892DF3A57FA7FFB3276192BC0EAA252ED45A865E3B093A3D0DCE5BE29E92F1392CE7DE321E3E5C52B3A")},
+	},
+	ProductMifareDESFireEV3: {
+		{"NXP MIFARE DESFire EV3", "secp224r1", mustDecodeHex("041DB46C145D0A36539C6544BD6D9B0AA62FF91EC48CBC6ABAE36E0089A46F0D08C8A715EA40A63313B92E90DDC1730230E0458A33276FB743")},
+	},
+}
+
+var ntag21xOriginalityKey = OriginalityKey{"NXP NTAG21x", "secp128r1", mustDecodeHex("04494E1A386D3D3CFE3DC10E5DE68A499B1C202DB5B132393E89ED19FE5BE8BC61")}
+
+func mustDecodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// VerifyOriginalitySignature 使用key验证uid的签名 签名为 r || s
+func VerifyOriginalitySignature(key OriginalityKey, uid, sig []byte) (bool, error) {
+	c, ok := ecCurves[key.Curve]
+	if !ok {
+		return false, fmt.Errorf("unsupported curve %q", key.Curve)
+	}
+	pub, err := c.parsePoint(key.PublicKey)
+	if err != nil {
+		return false, err
+	}
+	if len(sig) != 2*c.size {
+		return false, fmt.Errorf("signature length must be %d", 2*c.size)
+	}
+	r := new(big.Int).SetBytes(sig[:c.size])
+	s := new(big.Int).SetBytes(sig[c.size:])
+	return c.verify(pub, uid, r, s), nil
+}
+
+// SignatureStatus 原厂签名的验证结果
+type SignatureStatus int
+
+const (
+	SignatureUnknown  SignatureStatus = iota // 没有该型号的公钥或者卡片不支持读取签名
+	SignatureVerified                        // 签名有效 卡片为NXP原厂芯片
+	SignatureInvalid                         // 签名无效 卡片可能是仿制的
+)
+
+func (s SignatureStatus) String() string {
+	switch s {
+	case SignatureUnknown:
+		return "unknown"
+	case SignatureVerified:
+		return "verified"
+	case SignatureInvalid:
+		return "invalid"
+	}
+	return fmt.Sprintf("SignatureStatus(%d)", int(s))
+}
+
+// OriginalityResult 原厂签名的检查结果
+type OriginalityResult struct {
+	Status    SignatureStatus
+	Product   Product
+	UID       []byte
+	Signature []byte
+	Key       *OriginalityKey // 验证成功时使用的公钥
+}
+
+func (r *OriginalityResult) String() string {
+	if r.Key != nil {
+		return fmt.Sprintf("%s: signature %s by %s", r.Product, r.Status, r.Key.Name)
+	}
+	return fmt.Sprintf("%s: signature %s", r.Product, r.Status)
+}
+
+// VerifyOriginality 使用 OriginalityKeys 中该型号的公钥依次验证签名
+// 没有公钥时结果为 SignatureUnknown 全0的签名(未写入签名)视为无效
+func VerifyOriginality(product Product, uid, sig []byte) *OriginalityResult {
+	result := &OriginalityResult{Status: SignatureUnknown, Product: product, UID: uid, Signature: sig}
+	keys := OriginalityKeys[product]
+	if len(keys) == 0 {
+		return result
+	}
+	result.Status = SignatureInvalid
+	if len(sig) == 0 || bytes.Count(sig, []byte{0x00}) == len(sig) {
+		return result
+	}
+	for i := range keys {
+		if ok, err := VerifyOriginalitySignature(keys[i], uid, sig); err == nil && ok {
+			result.Status, result.Key = SignatureVerified, &keys[i]
+			break
+		}
+	}
+	return result
+}
+
+// CheckOriginality 识别目标 读取并验证原厂签名 不支持读取签名的型号结果为 SignatureUnknown
+func (p *Pn532) CheckOriginality(t *TargetA) (*OriginalityResult, error) {
+	info, err := p.Identify(t)
+	if err != nil {
+		return nil, err
+	}
+	var sig []byte
+	switch info.Product {
+	case ProductMifareUltralightEV1, ProductNTAG213, ProductNTAG215, ProductNTAG216, ProductNTAGI2C:
+		u, err := p.Ultralight(t)
+		if err != nil {
+			return nil, err
+		}
+		if sig, err = u.ReadSig(); err != nil {
+			return nil, err
+		}
+	case ProductMifareDESFireEV2, ProductMifareDESFireEV3:
+		if sig, err = p.readDESFireSig(); err != nil {
+			return nil, err
+		}
+	default:
+		return &OriginalityResult{Status: SignatureUnknown, Product: info.Product, UID: t.UID}, nil
+	}
+	return VerifyOriginality(info.Product, t.UID, sig), nil
+}
+
+// readDESFireSig 未认证时以明文读取56字节的签名
+func (p *Pn532) readDESFireSig() ([]byte, error) {
+	resp, err := p.InDataExchange([]byte{0x3C, 0x00})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp) < 1 {
+		return nil, errors.New("empty DESFire response")
+	}
+	if status := DESFireStatus(resp[0]); status != DESFireOK {
+		return nil, status
+	}
+	if len(resp) != 57 {
+		return nil, errors.New("unexpected DESFire Read_Sig response")
+	}
+	return resp[1:], nil
+}
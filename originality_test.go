@@ -0,0 +1,124 @@
+package pn532
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+// signOriginality 用私钥d和随机数k按照NXP的方式对UID签名
+func signOriginality(c *ecCurve, d, k *big.Int, uid []byte) []byte {
+	r := new(big.Int).Mod(c.scalarMult(c.g, k).x, c.n)
+	s := new(big.Int).Mul(r, d)
+	s.Add(s, new(big.Int).SetBytes(uid))
+	s.Mul(s, new(big.Int).ModInverse(k, c.n))
+	s.Mod(s, c.n)
+	return append(r.FillBytes(make([]byte, c.size)), s.FillBytes(make([]byte, c.size))...)
+}
+
+func publicKeyBytes(c *ecCurve, pt *ecPoint) []byte {
+	out := []byte{0x04}
+	out = append(out, pt.x.FillBytes(make([]byte, c.size))...)
+	return append(out, pt.y.FillBytes(make([]byte, c.size))...)
+}
+
+func TestOriginalityKeys(t *testing.T) {
+	for product, keys := range OriginalityKeys {
+		for _, key := range keys {
+			c := ecCurves[key.Curve]
+			if c == nil {
+				t.Errorf("%s: unknown curve %s", product, key.Curve)
+				continue
+			}
+			if _, err := c.parsePoint(key.PublicKey); err != nil {
+				t.Errorf("%s: %v", key.Name, err)
+			}
+		}
+	}
+	// 生成元的阶为n
+	for name, c := range ecCurves {
+		if !c.onCurve(c.g) || c.scalarMult(c.g, c.n) != nil {
+			t.Errorf("invalid curve parameters: %s", name)
+		}
+	}
+}
+
+func TestVerifyOriginalitySecp128r1(t *testing.T) {
+	uid := mustHex("04C1A2B3C4D580")
+	d := hexInt("1C5E2BF8D3A7A4F0B1D9C6E3F2A1B098")
+	key := OriginalityKey{"test", "secp128r1", publicKeyBytes(secp128r1, secp128r1.scalarMult(secp128r1.g, d))}
+	sig := signOriginality(secp128r1, d, hexInt("5A3C6F1E9B2D4A7C8E0F1D2C3B4A5968"), uid)
+
+	if ok, err := VerifyOriginalitySignature(key, uid, sig); err != nil || !ok {
+		t.Fatalf("valid signature rejected: %v", err)
+	}
+	uid[6] ^= 0x01
+	if ok, _ := VerifyOriginalitySignature(key, uid, sig); ok {
+		t.Error("signature accepted for another UID")
+	}
+	uid[6] ^= 0x01
+
+	saved := OriginalityKeys[ProductNTAG213]
+	defer func() { OriginalityKeys[ProductNTAG213] = saved }()
+	OriginalityKeys[ProductNTAG213] = append([]OriginalityKey{ntag21xOriginalityKey}, key)
+	result := VerifyOriginality(ProductNTAG213, uid, sig)
+	if result.Status != SignatureVerified || result.Key.Name != "test" {
+		t.Errorf("unexpected result: %s", result)
+	}
+	if result := VerifyOriginality(ProductNTAG213, uid, make([]byte, 32)); result.Status != SignatureInvalid {
+		t.Errorf("empty signature accepted: %s", result)
+	}
+	if result := VerifyOriginality(ProductMifareClassic1K, uid, sig); result.Status != SignatureUnknown {
+		t.Errorf("unexpected result: %s", result)
+	}
+}
+
+func TestVerifyOriginalitySecp224r1(t *testing.T) {
+	// 与标准库的ECDSA签名交叉验证
+	priv, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid := mustHex("04518AB2C36F80")
+	r, s, err := ecdsa.Sign(rand.Reader, priv, uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := OriginalityKey{"test", "secp224r1", publicKeyBytes(secp224r1, &ecPoint{priv.X, priv.Y})}
+	sig := append(r.FillBytes(make([]byte, 28)), s.FillBytes(make([]byte, 28))...)
+	if ok, err := VerifyOriginalitySignature(key, uid, sig); err != nil || !ok {
+		t.Fatalf("valid signature rejected: %v", err)
+	}
+	sig[0] ^= 0x80
+	if ok, _ := VerifyOriginalitySignature(key, uid, sig); ok {
+		t.Error("modified signature accepted")
+	}
+	if _, err := VerifyOriginalitySignature(key, uid, sig[:32]); err == nil {
+		t.Error("short signature accepted")
+	}
+}
+
+func TestReadDESFireSig(t *testing.T) {
+	sig := make([]byte, 56)
+	for i := range sig {
+		sig[i] = byte(i)
+	}
+	var status byte
+	p, _ := newFakePn532(func(cmd []byte) []byte {
+		return append([]byte{cmd[0] + 1, 0x00, status}, sig...)
+	})
+	p.trackTargets([]Target{&TargetA{targetBase: targetBase{tg: 0x01}}})
+	got, err := p.readDESFireSig()
+	if err != nil || !bytes.Equal(got, sig) {
+		t.Errorf("unexpected signature: % X %v", got, err)
+	}
+	// 0x90是ISO封装的SW1 不是原生命令的状态码
+	for _, status = range []byte{0x90, byte(DESFireAuthenticationError)} {
+		if _, err := p.readDESFireSig(); err != DESFireStatus(status) {
+			t.Errorf("%#02X: unexpected error: %v", status, err)
+		}
+	}
+}
@@ -5,13 +5,18 @@ import (
 	"errors"
 	"github.com/asjdf/pn532/command"
 	"go.bug.st/serial"
-	"log"
 	"strings"
 	"io"
+	"time"
 )
 
+// 卡片类型 即InListPassiveTarget的BrTy
 const (
-	ISO14443A = 0x00 // 卡片类型
+	ISO14443A = 0x00 // 106 kbps type A (ISO/IEC14443 Type A)
+	FeliCa212 = 0x01 // 212 kbps (FeliCa polling)
+	FeliCa424 = 0x02 // 424 kbps (FeliCa polling)
+	ISO14443B = 0x03 // 106 kbps type B (ISO/IEC14443-3B)
+	Jewel     = 0x04 // 106 kbps Innovision Jewel tag
 )
 
 type Pn532 struct {
@@ -19,6 +24,13 @@ type Pn532 struct {
 	wakeup bool
 	logger Logger
 
+	allowRegulationTest bool // 是否允许进入射频规范测试模式
+	inRegulationTest    bool
+	noAutoRATS          bool // SetParameters 关闭了AutoRATS
+
+	targets []Target // 当前PN532中已激活的目标
+	current Target   // 当前选择的目标 InDataExchange等命令会使用它的Tg
+
 	respBuf chan byte
 	Resp    chan *RespFrame
 }
@@ -42,6 +54,8 @@ type Config struct {
 	Port string // 串口号 例如 COM1 或者 /dev/ttyUSB0
 	*serial.Mode
 	Logger Logger
+
+	AllowRFRegulationTest bool // 允许调用 RFRegulationTest 仅用于实验室认证测试 生产环境请勿开启
 }
 
 func InitWithConf(conf *Config) (*Pn532, error) {
@@ -57,6 +71,8 @@ func InitWithConf(conf *Config) (*Pn532, error) {
 		respBuf: make(chan byte),
 		Resp:    make(chan *RespFrame),
 		logger:  conf.Logger,
+
+		allowRegulationTest: conf.AllowRFRegulationTest,
 	}
 	pn.initSerialReader()
 	return pn, nil
@@ -225,6 +241,7 @@ func (p *Pn532) WriteFrame(data []byte) error {
 		frame = append(command.WakeUp, frame...)
 		p.wakeup = true // 虽然这将会导致竞争问题 但是鉴于开发者不太会同时操作睡眠和唤醒 所以不做更多处理
 	}
+	p.inRegulationTest = false // 任何命令都会使PN532退出射频规范测试模式
 	p.logger.Debugf("write: % #X", frame)
 	_, err := p.port.Write(frame)
 	return err
@@ -264,6 +281,40 @@ func (p *Pn532) WaitInfoFrame() (*InfoFrame, error) {
 	}
 }
 
+// WaitInfoFrameTimeout 等待响应帧 超过timeout仍未收到时返回错误
+func (p *Pn532) WaitInfoFrameTimeout(timeout time.Duration) (*InfoFrame, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case resp := <-p.Resp:
+			if resp.Type == NormalFrame {
+				return Decode(resp.Raw)
+			}
+			p.logger.Debugf("receive unexpect frame: % #X", resp.Raw)
+		case <-timer.C:
+			return nil, errors.New("wait info frame timeout")
+		}
+	}
+}
+
+// execute 发送命令并等待响应帧 校验响应码后返回响应码之后的数据
+func (p *Pn532) execute(cmd []byte) ([]byte, error) {
+	if success, err := p.SendCommand(cmd); err != nil {
+		return nil, err
+	} else if !success {
+		return nil, errors.New("send command failed")
+	}
+	resp, err := p.WaitInfoFrame()
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Data) == 0 || resp.Data[0] != cmd[0]+1 {
+		return nil, errors.New("command resp error")
+	}
+	return resp.Data[1:], nil
+}
+
 // FirmwareVersion 获取固件版本
 func (p *Pn532) FirmwareVersion() ([]byte, error) {
 	if success, err := p.SendCommand([]byte{command.GetFirmwareVersion}); err != nil {
@@ -350,99 +401,77 @@ func (p *Pn532) SetParameters(NADUsed, DIDUsed, AutoATR_RES, AutoRATS, ISO14443_
 		return false, err
 	}
 	p.logger.Debugf("SetParameters: % #X", resp.Data)
-	return resp.Data[0] == command.SetParameters+1, nil
+	if resp.Data[0] != command.SetParameters+1 {
+		return false, nil
+	}
+	p.noAutoRATS = !AutoRATS
+	return true, nil
 }
 
-// ReadPassiveTarget 读卡 并返回读到的uid
+// ReadPassiveTarget 读卡 并返回读到的uid 需要完整的目标信息时使用 InListPassiveTarget
 func (p *Pn532) ReadPassiveTarget(cardBaud byte) ([]byte, error) {
 	// 532最多一次可以识读2张卡 但如果是Jewel卡 一次只能读一张 因为读两张意义不大 所以这里直接写死一张
-	if success, err := p.SendCommand([]byte{command.InListPassiveTarget, 0x01, cardBaud}); err != nil {
-		return nil, err
-	} else if !success {
-		return nil, errors.New("send command failed")
-	}
-
-	resp, err := p.WaitInfoFrame()
+	targets, err := p.InListPassiveTarget(0x01, cardBaud)
 	if err != nil {
 		return nil, err
 	}
-	if resp.Data[1] != 0x01 {
-		return nil, errors.New("more than one passive target detected")
-	}
-	if resp.Data[6] > 0x07 {
-		return nil, errors.New("found card with unexpected long uid length")
+	if len(targets) != 1 {
+		return nil, errors.New("no passive target detected")
 	}
-	return resp.Data[7 : 7+resp.Data[6]], nil
+	return targets[0].ID(), nil
 }
 
-// InAutoPoll 读卡 并返回读到的uid
-// PollNr specifies the number of polling (one polling is a polling for each Type j).
+// InAutoPoll 轮询卡片 并返回读到的所有目标及其类型
+// PollNr specifies the number of polling (one polling is a polling for each Type j), 0xFF means endless polling.
 // period (0x01-0x0F) indicates the polling period in units of 150 ms.
-// Type 1 indicates the mandatory target type to be polled at the 1st time.
-func (p *Pn532) InAutoPoll(PollNr, Period byte, Type ...byte) ([]byte, error) { // 实际上可以考虑返回[][]byte
+// Type 1 indicates the mandatory target type to be polled at the 1st time. 参见 PollType* 常量
+func (p *Pn532) InAutoPoll(PollNr, Period byte, Type ...byte) ([]PolledTarget, error) {
 	if PollNr < 0x01 {
 		return nil, errors.New("poll number must be greater than 0x01")
 	}
 	if Period < 0x01 || Period > 0x0F {
 		return nil, errors.New("period must be between 0x01 and 0x0F")
 	}
-	if len(Type) > 254 {
-		return nil, errors.New("type length must be less than 254")
+	if len(Type) < 1 || len(Type) > 15 {
+		return nil, errors.New("type number must be between 1 and 15")
 	}
-	if success, err := p.SendCommand(append([]byte{
+	resp, err := p.execute(append([]byte{
 		command.InAutoPoll,
 		PollNr,
-		Period,
-		byte(len(Type))},
-		Type...)); err != nil {
+		Period},
+		Type...))
+	if err != nil {
 		return nil, err
-	} else if !success {
-		return nil, errors.New("send command failed")
 	}
-
-	resp, err := p.WaitInfoFrame()
+	polled, err := decodeAutoPollResp(resp)
 	if err != nil {
 		return nil, err
 	}
-	if resp.Data[0] != command.InAutoPoll+1 {
-		return nil, errors.New("command resp error")
-	}
-	if resp.Data[1] != 0x01 {
-		return nil, errors.New("more than one passive target detected")
+	targets := make([]Target, 0, len(polled))
+	for _, t := range polled {
+		targets = append(targets, t.Target)
 	}
-	return resp.Data[9 : 9+resp.Data[8]], nil
+	p.trackTargets(targets)
+	return polled, nil
 }
 
 // MifareClassicAuthenticateBlock 验证区块密码  keyType 为设置验证A密码或B密码 blockNum为块号
+// uid 可以是完整的4/7/10字节UID 认证时只使用最后一级级联的4个字节
+// 认证失败后卡片会进入HALT状态 需要重新激活 使用 MifareClassicAuthenticate 可以自动处理
 func (p *Pn532) MifareClassicAuthenticateBlock(uid []byte, blockNum byte, keyType byte, key []byte) (bool, error) {
-	if len(key) != 6 {
-		log.Fatalf("key length must be 6")
-	}
-	if !(len(uid) >= 4 && len(uid) <= 7) {
-		log.Fatalf("uid length must be more than 3 and less than 8")
-	}
-	if keyType != command.MifareCmdAuthA && keyType != command.MifareCmdAuthB {
-		log.Fatalf("keyType must be 0x60 or 0x61")
-	}
-	cmd := []byte{
-		command.InDataExchange,
-		0x01, // 卡最多数量
-		keyType,
-		blockNum,
+	cmd, err := classicAuthCommand(uid, blockNum, keyType, key)
+	if err != nil {
+		return false, err
 	}
-	cmd = append(cmd, key...)
-	cmd = append(cmd, uid...)
-
-	if success, err := p.SendCommand(cmd); err != nil {
+	tg, err := p.currentTg()
+	if err != nil {
 		return false, err
-	} else if !success {
-		return false, errors.New("send command failed")
 	}
-	resp, err := p.WaitInfoFrame()
+	resp, err := p.execute(append([]byte{command.InDataExchange, tg}, cmd...))
 	if err != nil {
 		return false, err
 	}
-	if resp.Data[1] == 0x00 {
+	if len(resp) > 0 && resp[0] == 0x00 {
 		return true, nil
 	} else {
 		return false, nil
@@ -450,7 +479,11 @@ func (p *Pn532) MifareClassicAuthenticateBlock(uid []byte, blockNum byte, keyTyp
 }
 
 func (p *Pn532) MifareClassicReadBlock(blockNum byte) ([]byte, error) {
-	if success, err := p.SendCommand([]byte{command.InDataExchange, 0x01, command.MifareCmdRead, blockNum}); err != nil {
+	tg, err := p.currentTg()
+	if err != nil {
+		return nil, err
+	}
+	if success, err := p.SendCommand([]byte{command.InDataExchange, tg, command.MifareCmdRead, blockNum}); err != nil {
 		return nil, err
 	} else if !success {
 		return nil, errors.New("send command failed")
@@ -468,9 +501,13 @@ func (p *Pn532) MifareClassicReadBlock(blockNum byte) ([]byte, error) {
 
 func (p *Pn532) MifareClassicWriteBlock(blockNum byte, data []byte) (bool, error) {
 	if len(data) != 16 {
-		log.Fatalf("data length must be 16")
+		return false, errors.New("data length must be 16")
+	}
+	tg, err := p.currentTg()
+	if err != nil {
+		return false, err
 	}
-	if success, err := p.SendCommand(append([]byte{command.InDataExchange, 0x01, command.MifareCmdWrite, blockNum}, data...)); err != nil {
+	if success, err := p.SendCommand(append([]byte{command.InDataExchange, tg, command.MifareCmdWrite, blockNum}, data...)); err != nil {
 		return false, err
 	} else if !success {
 		return false, errors.New("send command failed")
@@ -0,0 +1,44 @@
+package pn532
+
+import (
+	"errors"
+	"github.com/asjdf/pn532/command"
+)
+
+// RegisterValue 寄存器地址与值
+type RegisterValue struct {
+	Addr  uint16
+	Value byte
+}
+
+// ReadRegister 读取寄存器 按地址顺序返回各寄存器的值
+func (p *Pn532) ReadRegister(addrs ...uint16) ([]byte, error) {
+	if len(addrs) == 0 {
+		return nil, errors.New("no register to read")
+	}
+	cmd := []byte{command.ReadRegister}
+	for _, addr := range addrs {
+		cmd = append(cmd, byte(addr>>8), byte(addr))
+	}
+	resp, err := p.execute(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp) != len(addrs) {
+		return nil, errors.New("command resp error")
+	}
+	return resp, nil
+}
+
+// WriteRegister 按顺序写入寄存器
+func (p *Pn532) WriteRegister(regs ...RegisterValue) error {
+	if len(regs) == 0 {
+		return nil
+	}
+	cmd := []byte{command.WriteRegister}
+	for _, reg := range regs {
+		cmd = append(cmd, byte(reg.Addr>>8), byte(reg.Addr), reg.Value)
+	}
+	_, err := p.execute(cmd)
+	return err
+}
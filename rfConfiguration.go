@@ -0,0 +1,267 @@
+package pn532
+
+import (
+	"errors"
+	"fmt"
+	"github.com/asjdf/pn532/command"
+	"time"
+)
+
+// RFConfiguration 配置PN532的射频参数 data为CfgItem之后的ConfigurationData
+func (p *Pn532) RFConfiguration(cfgItem byte, data ...byte) error {
+	_, err := p.execute(append([]byte{command.RFConfiguration, cfgItem}, data...))
+	if err != nil {
+		return err
+	}
+	p.logger.Debugf("RFConfiguration: %#X % #X", cfgItem, data)
+	return nil
+}
+
+// RFField 打开或关闭射频场
+// autoRFCA: If true, the PN532 will use Automatic RF Collision Avoidance before switching on the field.
+// rfOn: If true, the PN532 will switch on the RF field immediately.
+func (p *Pn532) RFField(autoRFCA, rfOn bool) error {
+	var cfg byte
+	if rfOn {
+		cfg |= 0x01
+	}
+	if autoRFCA {
+		cfg |= 0x02
+	}
+	return p.RFConfiguration(command.RFCfgField, cfg)
+}
+
+// RFTimeoutNone 超时编码 0x00 表示不设置超时
+const RFTimeoutNone byte = 0x00
+
+// RFTimeoutMax 超时编码的最大值 对应约3.28s
+const RFTimeoutMax byte = 0x10
+
+// RFTimeoutDuration 将超时编码转换为时长 编码n对应 100µs * 2^(n-1)
+func RFTimeoutDuration(code byte) time.Duration {
+	if code == RFTimeoutNone || code > RFTimeoutMax {
+		return 0
+	}
+	return 100 * time.Microsecond << (code - 1)
+}
+
+// RFTimeoutCode 返回不短于d的最小超时编码 超出范围时返回RFTimeoutMax 此时实际超时短于d 需要调用方补足剩余的等待
+func RFTimeoutCode(d time.Duration) byte {
+	for code := byte(0x01); code < RFTimeoutMax; code++ {
+		if RFTimeoutDuration(code) >= d {
+			return code
+		}
+	}
+	return RFTimeoutMax
+}
+
+// RFVariousTimings 设置超时时间 编码取值范围为 0x00-0x10 参见 RFTimeoutDuration
+// atrResTimeout: timeout value used for ATR_RES, default 0x0B (102.4 ms).
+// retryTimeout: timeout value used during InCommunicateThru, default 0x0A (51.2 ms).
+func (p *Pn532) RFVariousTimings(atrResTimeout, retryTimeout byte) error {
+	if atrResTimeout > RFTimeoutMax {
+		return errors.New("ATR_RES timeout must be between 0x00 and 0x10")
+	}
+	if retryTimeout > RFTimeoutMax {
+		return errors.New("retry timeout must be between 0x00 and 0x10")
+	}
+	return p.RFConfiguration(command.RFCfgVariousTimings, 0x00, atrResTimeout, retryTimeout) // 第一个字节为RFU
+}
+
+// RFMaxRtyCOM 设置InCommunicateThru和InDataExchange在超时时的重试次数 默认0x00即不重试
+func (p *Pn532) RFMaxRtyCOM(maxRtyCOM byte) error {
+	return p.RFConfiguration(command.RFCfgMaxRtyCOM, maxRtyCOM)
+}
+
+// RFMaxRetryInfinite 用于MxRtyPassiveActivation 表示无限重试
+const RFMaxRetryInfinite byte = 0xFF
+
+// RFMaxRetries 设置各种激活过程的重试次数
+// mxRtyATR: number of times the PN532 retries to send ATR_REQ, default 0xFF.
+// mxRtyPSL: number of times the PN532 retries to send PSL_REQ or QPPS_REQ, default 0x01.
+// mxRtyPassiveActivation: number of retries during InListPassiveTarget,
+// 0xFF means infinite retries (default), 0x00 means try only once.
+// 若希望 ReadPassiveTarget 在没有卡时返回 而不是一直等待 需要将 mxRtyPassiveActivation 设为 0xFF 以外的值
+func (p *Pn532) RFMaxRetries(mxRtyATR, mxRtyPSL, mxRtyPassiveActivation byte) error {
+	return p.RFConfiguration(command.RFCfgMaxRetries, mxRtyATR, mxRtyPSL, mxRtyPassiveActivation)
+}
+
+// AnalogSettings106A 106 kbps type A 的模拟参数 各字段为对应CIU寄存器的值
+type AnalogSettings106A struct {
+	RFCfg          byte
+	GsNOn          byte
+	CWGsP          byte // 6 bits
+	ModGsP         byte // 6 bits
+	DemodWhenRFOn  byte
+	RxThreshold    byte
+	DemodWhenRFOff byte
+	GsNOff         byte
+	ModWidth       byte
+	MifNFC         byte
+	TxBitPhase     byte
+}
+
+// DefaultAnalogSettings106A 用户手册中给出的默认值
+func DefaultAnalogSettings106A() *AnalogSettings106A {
+	return &AnalogSettings106A{
+		RFCfg:          0x59,
+		GsNOn:          0xF4,
+		CWGsP:          0x3F,
+		ModGsP:         0x11,
+		DemodWhenRFOn:  0x4D,
+		RxThreshold:    0x85,
+		DemodWhenRFOff: 0x61,
+		GsNOff:         0x6F,
+		ModWidth:       0x26,
+		MifNFC:         0x62,
+		TxBitPhase:     0x87,
+	}
+}
+
+func (s *AnalogSettings106A) bytes() ([]byte, error) {
+	if err := checkRFCfg(s.RFCfg); err != nil {
+		return nil, err
+	}
+	if err := checkGsP("CWGsP", s.CWGsP); err != nil {
+		return nil, err
+	}
+	if err := checkGsP("ModGsP", s.ModGsP); err != nil {
+		return nil, err
+	}
+	return []byte{
+		s.RFCfg, s.GsNOn, s.CWGsP, s.ModGsP, s.DemodWhenRFOn, s.RxThreshold,
+		s.DemodWhenRFOff, s.GsNOff, s.ModWidth, s.MifNFC, s.TxBitPhase,
+	}, nil
+}
+
+// RFAnalogSettings106A 设置106 kbps type A的模拟参数
+func (p *Pn532) RFAnalogSettings106A(s *AnalogSettings106A) error {
+	data, err := s.bytes()
+	if err != nil {
+		return err
+	}
+	return p.RFConfiguration(command.RFCfgAnalogSettings106A, data...)
+}
+
+// AnalogSettings212 212/424 kbps 的模拟参数
+type AnalogSettings212 struct {
+	RFCfg          byte
+	GsNOn          byte
+	CWGsP          byte // 6 bits
+	ModGsP         byte // 6 bits
+	DemodWhenRFOn  byte
+	RxThreshold    byte
+	DemodWhenRFOff byte
+	GsNOff         byte
+}
+
+// DefaultAnalogSettings212 用户手册中给出的默认值
+func DefaultAnalogSettings212() *AnalogSettings212 {
+	return &AnalogSettings212{
+		RFCfg:          0x69,
+		GsNOn:          0xFF,
+		CWGsP:          0x3F,
+		ModGsP:         0x11,
+		DemodWhenRFOn:  0x41,
+		RxThreshold:    0x85,
+		DemodWhenRFOff: 0x61,
+		GsNOff:         0x6F,
+	}
+}
+
+func (s *AnalogSettings212) bytes() ([]byte, error) {
+	if err := checkRFCfg(s.RFCfg); err != nil {
+		return nil, err
+	}
+	if err := checkGsP("CWGsP", s.CWGsP); err != nil {
+		return nil, err
+	}
+	if err := checkGsP("ModGsP", s.ModGsP); err != nil {
+		return nil, err
+	}
+	return []byte{
+		s.RFCfg, s.GsNOn, s.CWGsP, s.ModGsP, s.DemodWhenRFOn, s.RxThreshold,
+		s.DemodWhenRFOff, s.GsNOff,
+	}, nil
+}
+
+// RFAnalogSettings212 设置212/424 kbps的模拟参数
+func (p *Pn532) RFAnalogSettings212(s *AnalogSettings212) error {
+	data, err := s.bytes()
+	if err != nil {
+		return err
+	}
+	return p.RFConfiguration(command.RFCfgAnalogSettings212, data...)
+}
+
+// AnalogSettingsTypeB type B 的模拟参数
+type AnalogSettingsTypeB struct {
+	GsNOn       byte
+	ModGsP      byte // 6 bits
+	RxThreshold byte
+}
+
+// DefaultAnalogSettingsTypeB 用户手册中给出的默认值
+func DefaultAnalogSettingsTypeB() *AnalogSettingsTypeB {
+	return &AnalogSettingsTypeB{
+		GsNOn:       0xFF,
+		ModGsP:      0x17,
+		RxThreshold: 0x85,
+	}
+}
+
+// RFAnalogSettingsTypeB 设置type B的模拟参数
+func (p *Pn532) RFAnalogSettingsTypeB(s *AnalogSettingsTypeB) error {
+	if err := checkGsP("ModGsP", s.ModGsP); err != nil {
+		return err
+	}
+	return p.RFConfiguration(command.RFCfgAnalogSettingsTypeB, s.GsNOn, s.ModGsP, s.RxThreshold)
+}
+
+// AnalogSettings14443Rate ISO/IEC14443-4 协议下某一速率的模拟参数
+type AnalogSettings14443Rate struct {
+	RxThreshold byte
+	ModWidth    byte
+	MifNFC      byte
+}
+
+// AnalogSettings14443 ISO/IEC14443-4 协议下 212/424/848 kbps 的模拟参数
+type AnalogSettings14443 struct {
+	Rate212 AnalogSettings14443Rate
+	Rate424 AnalogSettings14443Rate
+	Rate848 AnalogSettings14443Rate
+}
+
+// DefaultAnalogSettings14443 用户手册中给出的默认值
+func DefaultAnalogSettings14443() *AnalogSettings14443 {
+	return &AnalogSettings14443{
+		Rate212: AnalogSettings14443Rate{RxThreshold: 0x85, ModWidth: 0x15, MifNFC: 0x8A},
+		Rate424: AnalogSettings14443Rate{RxThreshold: 0x85, ModWidth: 0x08, MifNFC: 0xB2},
+		Rate848: AnalogSettings14443Rate{RxThreshold: 0x85, ModWidth: 0x01, MifNFC: 0xDA},
+	}
+}
+
+// RFAnalogSettings14443 设置ISO/IEC14443-4协议下212/424/848 kbps的模拟参数
+func (p *Pn532) RFAnalogSettings14443(s *AnalogSettings14443) error {
+	data := make([]byte, 0, 9)
+	for _, r := range []AnalogSettings14443Rate{s.Rate212, s.Rate424, s.Rate848} {
+		data = append(data, r.RxThreshold, r.ModWidth, r.MifNFC)
+	}
+	return p.RFConfiguration(command.RFCfgAnalogSettings14443, data...)
+}
+
+// CIU_RFCfg 的 bit7 为保留位
+func checkRFCfg(v byte) error {
+	if v&0x80 != 0 {
+		return errors.New("RFCfg bit 7 is reserved and must be 0")
+	}
+	return nil
+}
+
+// CIU_CWGsP 与 CIU_ModGsP 只有低6位有效
+func checkGsP(name string, v byte) error {
+	if v > 0x3F {
+		return fmt.Errorf("%s must be between 0x00 and 0x3F", name)
+	}
+	return nil
+}
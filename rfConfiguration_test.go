@@ -0,0 +1,34 @@
+package pn532
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRFTimeoutCode(t *testing.T) {
+	if d := RFTimeoutDuration(0x0B); d != 102400*time.Microsecond {
+		t.Errorf("unexpected duration for 0x0B: %s", d)
+	}
+	if c := RFTimeoutCode(50 * time.Millisecond); c != 0x0A {
+		t.Errorf("unexpected code for 50ms: %#X", c)
+	}
+	if c := RFTimeoutCode(time.Hour); c != RFTimeoutMax {
+		t.Errorf("unexpected code for 1h: %#X", c)
+	}
+}
+
+func TestAnalogSettings106A(t *testing.T) {
+	s := DefaultAnalogSettings106A()
+	data, err := s.bytes()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(data) != 11 {
+		t.Errorf("unexpected length: %d", len(data))
+	}
+	s.CWGsP = 0x40
+	if _, err := s.bytes(); err == nil {
+		t.Error("expect error for CWGsP out of range")
+	}
+}
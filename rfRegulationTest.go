@@ -0,0 +1,87 @@
+package pn532
+
+import (
+	"errors"
+	"github.com/asjdf/pn532/command"
+)
+
+// TxMode RFRegulationTest 的参数 对应CIU_TxMode寄存器
+// bit 6-4: TxSpeed, bit 1-0: TxFraming, 其余位为0
+type TxMode byte
+
+// TxSpeed 射频规范测试的速率
+const (
+	TxSpeed106 byte = 0x00 // 106 kbps
+	TxSpeed212 byte = 0x01 // 212 kbps
+	TxSpeed424 byte = 0x02 // 424 kbps
+)
+
+// TxFraming 射频规范测试的帧格式
+const (
+	TxFramingMifare byte = 0x00 // Mifare / ISO/IEC14443A
+	TxFramingActive byte = 0x01 // Active mode
+	TxFramingFeliCa byte = 0x02 // FeliCa
+)
+
+// NewTxMode 根据速率与帧格式生成TxMode FeliCa帧格式只支持212/424 kbps
+func NewTxMode(speed, framing byte) (TxMode, error) {
+	if speed > TxSpeed424 {
+		return 0, errors.New("speed must be 106, 212 or 424 kbps")
+	}
+	if framing > TxFramingFeliCa {
+		return 0, errors.New("framing must be Mifare, active mode or FeliCa")
+	}
+	if framing == TxFramingFeliCa && speed == TxSpeed106 {
+		return 0, errors.New("FeliCa framing is only available at 212 or 424 kbps")
+	}
+	return TxMode(speed<<4 | framing), nil
+}
+
+// Speed 返回TxMode中的速率
+func (m TxMode) Speed() byte {
+	return byte(m) >> 4 & 0x07
+}
+
+// Framing 返回TxMode中的帧格式
+func (m TxMode) Framing() byte {
+	return byte(m) & 0x03
+}
+
+// RFRegulationTest 进入射频规范测试模式 PN532会持续发送载波与调制信号直到收到下一条命令
+// 该命令只会收到ACK 不会有响应帧 需要调用 StopRFRegulationTest 退出
+// 为防止生产环境误用 需要在Config中开启 AllowRFRegulationTest
+func (p *Pn532) RFRegulationTest(mode TxMode) error {
+	if !p.allowRegulationTest {
+		return errors.New("rf regulation test is not allowed, enable it with Config.AllowRFRegulationTest")
+	}
+	if mode.Speed() > TxSpeed424 || mode.Framing() > TxFramingFeliCa || byte(mode)&0x8C != 0 {
+		return errors.New("invalid tx mode")
+	}
+	if success, err := p.SendCommand([]byte{command.RFRegulationTest, byte(mode)}); err != nil {
+		return err
+	} else if !success {
+		return errors.New("send command failed")
+	}
+	p.inRegulationTest = true
+	p.logger.Infof("enter rf regulation test, tx mode: %#X", byte(mode))
+	return nil
+}
+
+// InRFRegulationTest 是否处于射频规范测试模式
+func (p *Pn532) InRFRegulationTest() bool {
+	return p.inRegulationTest
+}
+
+// StopRFRegulationTest 退出射频规范测试模式
+// PN532只有在收到新命令时才会退出 这里发送GetFirmwareVersion并像普通命令一样读取其响应
+// 避免迟到的响应被当作下一条命令的结果
+func (p *Pn532) StopRFRegulationTest() error {
+	if !p.inRegulationTest {
+		return nil
+	}
+	if _, err := p.execute([]byte{command.GetFirmwareVersion}); err != nil {
+		return err
+	}
+	p.logger.Infof("leave rf regulation test")
+	return nil
+}
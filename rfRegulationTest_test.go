@@ -0,0 +1,24 @@
+package pn532
+
+import "testing"
+
+func TestNewTxMode(t *testing.T) {
+	mode, err := NewTxMode(TxSpeed424, TxFramingFeliCa)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if mode != 0x22 || mode.Speed() != TxSpeed424 || mode.Framing() != TxFramingFeliCa {
+		t.Errorf("unexpected tx mode: %#X", byte(mode))
+	}
+	if _, err := NewTxMode(TxSpeed106, TxFramingFeliCa); err == nil {
+		t.Error("expect error for FeliCa at 106 kbps")
+	}
+}
+
+func TestRFRegulationTestNotAllowed(t *testing.T) {
+	p := &Pn532{}
+	if err := p.RFRegulationTest(0x00); err == nil {
+		t.Error("expect error when regulation test is not allowed")
+	}
+}
@@ -0,0 +1,234 @@
+package pn532
+
+import (
+	"bytes"
+	"errors"
+	"github.com/asjdf/pn532/command"
+	"time"
+)
+
+// ErrNoTarget 没有可用于通信的目标
+var ErrNoTarget = errors.New("no selected target")
+
+// trackTargets 记录新识读到的目标 PN532在识读时会释放之前的目标 并选择第一个目标
+func (p *Pn532) trackTargets(targets []Target) {
+	for _, t := range p.targets {
+		t.base().state = TargetReleased
+	}
+	p.targets = append([]Target(nil), targets...) // 不与调用者共享切片
+	p.current = nil
+	for i, t := range targets {
+		if i == 0 {
+			t.base().state = TargetSelected
+			p.current = t
+		} else {
+			t.base().state = TargetDeselected
+		}
+	}
+}
+
+// CurrentTarget 返回当前选择的目标 没有时返回nil
+func (p *Pn532) CurrentTarget() Target {
+	return p.current
+}
+
+// currentTg 当前选择的目标的Tg 没有选择目标或者目标已释放时返回 ErrNoTarget
+func (p *Pn532) currentTg() (byte, error) {
+	if p.current == nil || p.current.State() != TargetSelected {
+		return 0, ErrNoTarget
+	}
+	return p.current.Tg(), nil
+}
+
+func (p *Pn532) isTracked(t Target) bool {
+	for _, tracked := range p.targets {
+		if tracked == t {
+			return true
+		}
+	}
+	return false
+}
+
+// InSelect 选择目标 之后的InDataExchange等命令都会与该目标通信
+func (p *Pn532) InSelect(t Target) error {
+	if t == nil || !p.isTracked(t) {
+		return errors.New("target is not listed by this device")
+	}
+	resp, err := p.execute([]byte{command.InSelect, t.Tg()})
+	if err != nil {
+		return err
+	}
+	if err := checkStatusResp(resp); err != nil {
+		return err
+	}
+	if p.current != nil && p.current != t {
+		p.current.base().state = TargetDeselected
+	}
+	t.base().state = TargetSelected
+	p.current = t
+	return nil
+}
+
+// InDeselect 取消选择目标 目标仍由PN532保存 可以通过InSelect重新选择 t为nil时取消选择所有目标
+func (p *Pn532) InDeselect(t Target) error {
+	tg := byte(0x00)
+	if t != nil {
+		if !p.isTracked(t) {
+			return errors.New("target is not listed by this device")
+		}
+		tg = t.Tg()
+	}
+	resp, err := p.execute([]byte{command.InDeselect, tg})
+	if err != nil {
+		return err
+	}
+	if err := checkStatusResp(resp); err != nil {
+		return err
+	}
+	for _, tracked := range p.targets {
+		if t == nil || tracked == t {
+			tracked.base().state = TargetDeselected
+		}
+	}
+	if t == nil || p.current == t {
+		p.current = nil
+	}
+	return nil
+}
+
+// InRelease 释放目标 释放后需要重新识读 t为nil时释放所有目标
+func (p *Pn532) InRelease(t Target) error {
+	tg := byte(0x00)
+	if t != nil {
+		if !p.isTracked(t) {
+			return errors.New("target is not listed by this device")
+		}
+		tg = t.Tg()
+	}
+	resp, err := p.execute([]byte{command.InRelease, tg})
+	if err != nil {
+		return err
+	}
+	if err := checkStatusResp(resp); err != nil {
+		return err
+	}
+	p.releaseTracked(t)
+	return nil
+}
+
+// releaseTracked 把目标标记为已释放并停止跟踪 t为nil时释放所有目标
+// p.targets 与识读时返回给调用者的切片不共享底层数组 这里同样使用新的切片
+func (p *Pn532) releaseTracked(t Target) {
+	remain := make([]Target, 0, len(p.targets))
+	for _, tracked := range p.targets {
+		if t == nil || tracked == t {
+			tracked.base().state = TargetReleased
+		} else {
+			remain = append(remain, tracked)
+		}
+	}
+	p.targets = remain
+	if t == nil || p.current == t {
+		p.current = nil
+	}
+}
+
+// 状态字节与Tg中的MI(More Information)位
+const statusMI byte = 0x40
+
+// maxExchangeData 普通帧中InDataExchange除Tg外最多可携带的数据长度
+const maxExchangeData = 252
+
+// InDataExchange 与当前选择的目标交换数据 返回目标的响应数据
+// 数据超过一帧时通过Tg中的MI位分段发送 响应中带有MI位时会自动取回剩余的数据
+func (p *Pn532) InDataExchange(data []byte) ([]byte, error) {
+	if p.current == nil || p.current.State() != TargetSelected {
+		return nil, ErrNoTarget
+	}
+	tg := p.current.Tg()
+	for len(data) > maxExchangeData {
+		if _, _, err := p.dataExchange(tg|statusMI, data[:maxExchangeData]); err != nil {
+			return nil, err
+		}
+		data = data[maxExchangeData:]
+	}
+	var out []byte
+	for {
+		status, resp, err := p.dataExchange(tg, data)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, resp...)
+		if status&statusMI == 0 {
+			return out, nil
+		}
+		data = nil
+	}
+}
+
+func (p *Pn532) dataExchange(tg byte, data []byte) (byte, []byte, error) {
+	resp, err := p.execute(append([]byte{command.InDataExchange, tg}, data...))
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := checkStatusResp(resp); err != nil {
+		return 0, nil, err
+	}
+	return resp[0], resp[1:], nil
+}
+
+// Transceive 与当前选择的目标交换数据 同 InDataExchange
+func (p *Pn532) Transceive(data []byte) ([]byte, error) {
+	return p.InDataExchange(data)
+}
+
+// Reactivate 重新激活进入IDLE或HALT状态的type A目标 例如命令失败或者认证失败之后
+// 目标对象保持不变 并重新成为当前选择的目标 如果卡片没有响应会重启一次射频场后再尝试
+func (p *Pn532) Reactivate(t *TargetA) error {
+	found, err := p.listByUID(t.UID)
+	if err == nil && found == nil {
+		// HALT状态的卡片可能不会响应 重启射频场使其复位
+		if err := p.RFField(false, false); err != nil {
+			return err
+		}
+		time.Sleep(10 * time.Millisecond)
+		if err := p.RFField(true, true); err != nil {
+			return err
+		}
+		found, err = p.listByUID(t.UID)
+	}
+	if err != nil {
+		return err
+	}
+	if found == nil {
+		return errors.New("target not found")
+	}
+	t.tg = found.tg
+	t.ATQA, t.SAK = found.ATQA, found.SAK
+	t.RawATS, t.ATS = found.RawATS, found.ATS
+	t.state = TargetSelected
+	found.state = TargetReleased
+	p.targets = []Target{t}
+	p.current = t
+	return nil
+}
+
+// listByUID 识读指定UID的type A目标 没有找到时返回nil
+func (p *Pn532) listByUID(uid []byte) (*TargetA, error) {
+	cascade, err := CascadeUID(uid)
+	if err != nil {
+		return nil, err
+	}
+	targets, err := p.InListPassiveTarget(0x01, ISO14443A, cascade...)
+	if err != nil {
+		return nil, err
+	}
+	if len(targets) != 1 {
+		return nil, nil
+	}
+	found, ok := targets[0].(*TargetA)
+	if !ok || !bytes.Equal(found.UID, uid) {
+		return nil, nil
+	}
+	return found, nil
+}
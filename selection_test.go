@@ -0,0 +1,145 @@
+package pn532
+
+import (
+	"bytes"
+	"errors"
+	"go.bug.st/serial"
+	"testing"
+	"time"
+)
+
+// fakePort 模拟连接PN532的串口 记录写入的帧 每条命令先回复ACK 再回复handle返回的数据
+type fakePort struct {
+	p      *Pn532
+	frames [][]byte
+	handle func(cmd []byte) []byte // cmd与返回值都不含TFI 返回值的第一个字节为命令码+1
+}
+
+func newFakePn532(handle func(cmd []byte) []byte) (*Pn532, *fakePort) {
+	port := &fakePort{handle: handle}
+	port.p = &Pn532{port: port, wakeup: true, logger: &SilentLogger{}, Resp: make(chan *RespFrame, 2)}
+	return port.p, port
+}
+
+func (f *fakePort) Write(b []byte) (int, error) {
+	f.frames = append(f.frames, append([]byte(nil), b...))
+	frame, err := Decode(b)
+	if err != nil {
+		return 0, err
+	}
+	resp := NewNormalFrame(f.handle(frame.Data))
+	resp.Tfi = 0xD5
+	resp.Dcs = resp.calcDcs()
+	f.p.Resp <- &RespFrame{Type: ACKFrame, Raw: []byte{0x00, 0x00, 0xFF, 0x00, 0xFF, 0x00}}
+	f.p.Resp <- &RespFrame{Type: NormalFrame, Raw: resp.Gen()}
+	return len(b), nil
+}
+
+func (f *fakePort) SetMode(*serial.Mode) error { return nil }
+
+func (f *fakePort) Read([]byte) (int, error) { return 0, errors.New("not supported") }
+
+func (f *fakePort) ResetInputBuffer() error { return nil }
+
+func (f *fakePort) ResetOutputBuffer() error { return nil }
+
+func (f *fakePort) SetDTR(bool) error { return nil }
+
+func (f *fakePort) SetRTS(bool) error { return nil }
+
+func (f *fakePort) GetModemStatusBits() (*serial.ModemStatusBits, error) {
+	return &serial.ModemStatusBits{}, nil
+}
+
+func (f *fakePort) SetReadTimeout(time.Duration) error { return nil }
+
+func (f *fakePort) Close() error { return nil }
+
+func (f *fakePort) Break(time.Duration) error { return nil }
+
+func TestTrackTargets(t *testing.T) {
+	p := &Pn532{}
+	if _, err := p.currentTg(); err != ErrNoTarget {
+		t.Errorf("expect ErrNoTarget, got %v", err)
+	}
+	if (&TargetA{}).State() != TargetUnknown {
+		t.Error("target not listed by a device should be unknown")
+	}
+	if _, err := p.InDataExchange([]byte{0x30, 0x00}); err != ErrNoTarget {
+		t.Errorf("expect ErrNoTarget, got %v", err)
+	}
+
+	first := []Target{&TargetA{targetBase: targetBase{tg: 0x01}}, &TargetA{targetBase: targetBase{tg: 0x02}}}
+	p.trackTargets(first)
+	if p.CurrentTarget() != first[0] || first[0].State() != TargetSelected || first[1].State() != TargetDeselected {
+		t.Error("unexpected state after listing targets")
+	}
+	if tg, err := p.currentTg(); err != nil || tg != 0x01 {
+		t.Errorf("unexpected current tg: %#X %v", tg, err)
+	}
+
+	second := []Target{&TargetA{targetBase: targetBase{tg: 0x01}}}
+	p.trackTargets(second)
+	if first[0].State() != TargetReleased || first[1].State() != TargetReleased {
+		t.Error("previous targets should be released")
+	}
+	if err := p.InSelect(first[1]); err == nil {
+		t.Error("expect error when selecting a released target")
+	}
+
+	// 释放目标不能修改识读时返回给调用者的切片
+	listed := []Target{&TargetA{targetBase: targetBase{tg: 0x01}}, &TargetA{targetBase: targetBase{tg: 0x02}}}
+	t1, t2 := listed[0], listed[1]
+	p.trackTargets(listed)
+	p.releaseTracked(t1)
+	if listed[0] != t1 || listed[1] != t2 {
+		t.Error("caller's target slice modified by release")
+	}
+	if t1.State() != TargetReleased || p.CurrentTarget() != nil || !p.isTracked(t2) || p.isTracked(t1) {
+		t.Error("unexpected state after releasing a target")
+	}
+	// 旧的MifareClassic*Block不能继续使用已释放目标的Tg
+	if _, err := p.MifareClassicReadBlock(0x04); err != ErrNoTarget {
+		t.Errorf("expect ErrNoTarget, got %v", err)
+	}
+}
+
+func TestReactivateSevenByteUID(t *testing.T) {
+	uid := mustHex("04112233445566")
+	p, port := newFakePn532(func(cmd []byte) []byte {
+		switch cmd[0] {
+		case 0x4A:
+			// 只有带级联标志的UID才能选中卡片
+			if bytes.Equal(cmd[3:], mustHex("8804112233445566")) {
+				return append(mustHex("4B01"+"01004400"+"07"), uid...)
+			}
+			return mustHex("4B00")
+		case 0x32:
+			return []byte{0x33}
+		}
+		return []byte{cmd[0] + 1}
+	})
+	target := &TargetA{targetBase: targetBase{tg: 0x02, state: TargetReleased}, UID: uid}
+	if err := p.Reactivate(target); err != nil {
+		t.Fatal(err)
+	}
+	// InListPassiveTarget MaxTg=1 BrTy=0x00 InitiatorData=88 UID0-2 UID3-6
+	want := mustHex("0000FF0CF4D44A01008804112233445566F000")
+	if len(port.frames) != 1 || !bytes.Equal(port.frames[0], want) {
+		t.Errorf("unexpected frames: % X", port.frames)
+	}
+	if target.Tg() != 0x01 || target.State() != TargetSelected || p.CurrentTarget() != target || target.ATQA != [2]byte{0x00, 0x44} {
+		t.Errorf("unexpected target: %+v", target)
+	}
+}
+
+func TestStatusError(t *testing.T) {
+	err := checkStatusResp([]byte{0x54})
+	var status StatusError
+	if !errors.As(err, &status) || status != StatusMifareAuth {
+		t.Errorf("unexpected status error: %v", err)
+	}
+	if err := checkStatus(0x40); err != nil {
+		t.Errorf("MI bit should not be treated as error: %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package pn532
+
+import (
+	"errors"
+	"fmt"
+)
+
+// StatusError PN532 响应中Status字节的错误码(低6位)
+type StatusError byte
+
+// 用户手册中定义的错误码
+const (
+	StatusTimeout          StatusError = 0x01 // Time Out, the target has not answered
+	StatusCRC              StatusError = 0x02 // A CRC error has been detected by the CIU
+	StatusParity           StatusError = 0x03 // A Parity error has been detected by the CIU
+	StatusBitCount         StatusError = 0x04 // During an anti-collision/select operation, an erroneous Bit Count has been detected
+	StatusFraming          StatusError = 0x05 // Framing error during Mifare operation
+	StatusBitCollision     StatusError = 0x06 // An abnormal bit-collision has been detected during bit wise anti-collision at 106 kbps
+	StatusBufferSize       StatusError = 0x07 // Communication buffer size insufficient
+	StatusRFBufferOverflow StatusError = 0x09 // RF Buffer overflow has been detected by the CIU
+	StatusRFFieldTimeout   StatusError = 0x0A // In active communication mode, the RF field has not been switched on in time by the counterpart
+	StatusRFProtocol       StatusError = 0x0B // RF Protocol error
+	StatusTemperature      StatusError = 0x0D // Temperature error: the internal temperature sensor has detected overheating
+	StatusInternalBuffer   StatusError = 0x0E // Internal buffer overflow
+	StatusInvalidParameter StatusError = 0x10 // Invalid parameter (range, format, …)
+	StatusDEPUnsupported   StatusError = 0x12 // DEP Protocol: The PN532 configured in target mode does not support the command received from the initiator
+	StatusDEPFormat        StatusError = 0x13 // DEP Protocol, Mifare or ISO/IEC14443-4: The data format does not match to the specification
+	StatusMifareAuth       StatusError = 0x14 // Mifare: Authentication error
+	StatusUIDCheckByte     StatusError = 0x23 // ISO/IEC14443-3: UID Check byte is wrong
+	StatusDEPInvalidState  StatusError = 0x25 // DEP Protocol: Invalid device state, the system is in a state which does not allow the operation
+	StatusNotAllowed       StatusError = 0x26 // Operation not allowed in this configuration (host controller interface)
+	StatusNotAcceptable    StatusError = 0x27 // This command is not acceptable due to the current context of the PN532
+	StatusReleased         StatusError = 0x29 // The PN532 configured as target has been released by its initiator
+	StatusCardIDMismatch   StatusError = 0x2A // PN532 and ISO/IEC14443-3B only: the ID of the card does not match
+	StatusCardDisappeared  StatusError = 0x2B // PN532 and ISO/IEC14443-3B only: the card previously activated has disappeared
+	StatusNFCID3Mismatch   StatusError = 0x2C // Mismatch between the NFCID3 initiator and the NFCID3 target in DEP 212/424 kbps passive
+	StatusOverCurrent      StatusError = 0x2D // An over-current event has been detected
+	StatusNADMissing       StatusError = 0x2E // NAD missing in DEP frame
+)
+
+var statusMessages = map[StatusError]string{
+	StatusTimeout:          "target has not answered",
+	StatusCRC:              "CRC error",
+	StatusParity:           "parity error",
+	StatusBitCount:         "erroneous bit count during anti-collision",
+	StatusFraming:          "framing error during Mifare operation",
+	StatusBitCollision:     "abnormal bit-collision during anti-collision",
+	StatusBufferSize:       "communication buffer size insufficient",
+	StatusRFBufferOverflow: "RF buffer overflow",
+	StatusRFFieldTimeout:   "RF field has not been switched on in time",
+	StatusRFProtocol:       "RF protocol error",
+	StatusTemperature:      "overheating",
+	StatusInternalBuffer:   "internal buffer overflow",
+	StatusInvalidParameter: "invalid parameter",
+	StatusDEPUnsupported:   "DEP command not supported",
+	StatusDEPFormat:        "data format does not match the specification",
+	StatusMifareAuth:       "Mifare authentication error",
+	StatusUIDCheckByte:     "UID check byte is wrong",
+	StatusDEPInvalidState:  "invalid device state",
+	StatusNotAllowed:       "operation not allowed in this configuration",
+	StatusNotAcceptable:    "command not acceptable in current context",
+	StatusReleased:         "released by initiator",
+	StatusCardIDMismatch:   "card ID does not match",
+	StatusCardDisappeared:  "card has disappeared",
+	StatusNFCID3Mismatch:   "NFCID3 mismatch",
+	StatusOverCurrent:      "over-current detected",
+	StatusNADMissing:       "NAD missing in DEP frame",
+}
+
+func (e StatusError) Error() string {
+	if msg, ok := statusMessages[e]; ok {
+		return fmt.Sprintf("pn532 status %#02X: %s", byte(e), msg)
+	}
+	return fmt.Sprintf("pn532 status %#02X", byte(e))
+}
+
+// checkStatus 检查Status字节 bit6为MI bit7为NAD 不属于错误码
+func checkStatus(status byte) error {
+	if code := status & 0x3F; code != 0 {
+		return StatusError(code)
+	}
+	return nil
+}
+
+// checkStatusResp 检查以Status字节开头的响应
+func checkStatusResp(resp []byte) error {
+	if len(resp) == 0 {
+		return errors.New("command resp error")
+	}
+	return checkStatus(resp[0])
+}
@@ -0,0 +1,289 @@
+package pn532
+
+import (
+	"errors"
+	"fmt"
+	"github.com/asjdf/pn532/command"
+)
+
+// Target InListPassiveTarget 识读到的目标 具体类型为 *TargetA *TargetFeliCa *TargetB *TargetJewel 之一
+type Target interface {
+	Tg() byte   // PN532分配的逻辑编号
+	BrTy() byte // 目标的调制类型 ISO14443A FeliCa212 FeliCa424 ISO14443B Jewel
+	ID() []byte // 目标的标识 type A为UID FeliCa为IDm type B为PUPI Jewel为JEWELID
+	State() TargetState
+	base() *targetBase
+}
+
+// TargetState 目标在PN532中的状态
+type TargetState int
+
+const (
+	TargetUnknown    TargetState = iota // 不是由本设备识读的目标 例如自行构造的结构体
+	TargetSelected                      // 当前正在通信的目标
+	TargetDeselected                    // 已取消选择 可以通过InSelect重新选择
+	TargetReleased                      // 已释放 需要重新识读
+)
+
+type targetBase struct {
+	tg    byte
+	brTy  byte
+	state TargetState
+}
+
+func (t *targetBase) Tg() byte {
+	return t.tg
+}
+
+func (t *targetBase) BrTy() byte {
+	return t.brTy
+}
+
+func (t *targetBase) State() TargetState {
+	return t.state
+}
+
+func (t *targetBase) base() *targetBase {
+	return t
+}
+
+// TargetA 106 kbps type A 目标
+type TargetA struct {
+	targetBase
+	ATQA   [2]byte // SENS_RES
+	SAK    byte    // SEL_RES
+	UID    []byte  // NFCID1 4、7或10字节
+	RawATS []byte  // 仅ISO/IEC14443-4卡片存在 第一个字节TL为ATS的长度
+	ATS    *ATS    // 解析后的ATS 没有ATS或者ATS格式错误时为nil
+}
+
+func (t *TargetA) ID() []byte {
+	return t.UID
+}
+
+// ISO14443_4 卡片是否兼容ISO/IEC14443-4
+func (t *TargetA) ISO14443_4() bool {
+	return t.SAK&0x20 != 0
+}
+
+// TargetFeliCa 212/424 kbps FeliCa 目标
+type TargetFeliCa struct {
+	targetBase
+	ResponseCode byte
+	IDm          [8]byte // NFCID2t
+	PMm          [8]byte
+	SystemCode   []byte // 仅当轮询时请求了系统码才存在 2字节
+}
+
+func (t *TargetFeliCa) ID() []byte {
+	return t.IDm[:]
+}
+
+// TargetB 106 kbps type B 目标
+type TargetB struct {
+	targetBase
+	ATQB      [12]byte // 0x50 PUPI(4) Application Data(4) Protocol Info(3)
+	ATTRIBRes []byte   // ATTRIB_RES
+}
+
+func (t *TargetB) ID() []byte {
+	return t.ATQB[1:5]
+}
+
+// PUPI Pseudo-Unique PICC Identifier
+func (t *TargetB) PUPI() []byte {
+	return t.ATQB[1:5]
+}
+
+// ApplicationData ATQB中的应用数据
+func (t *TargetB) ApplicationData() []byte {
+	return t.ATQB[5:9]
+}
+
+// ProtocolInfo ATQB中的协议信息
+func (t *TargetB) ProtocolInfo() []byte {
+	return t.ATQB[9:12]
+}
+
+// TargetJewel 106 kbps Innovision Jewel 目标
+type TargetJewel struct {
+	targetBase
+	SENSRes [2]byte
+	JewelID [4]byte
+}
+
+func (t *TargetJewel) ID() []byte {
+	return t.JewelID[:]
+}
+
+// feliCaDefaultPolling 系统码FFFF 请求系统码 时隙数1
+var feliCaDefaultPolling = []byte{0x00, 0xFF, 0xFF, 0x01, 0x00}
+
+// cascadeTag 级联标志 表示UID在下一级继续
+const cascadeTag byte = 0x88
+
+// CascadeUID 把4、7或10字节的UID转换为InListPassiveTarget所需的带级联标志的形式
+// 4字节: UID0-3 7字节: 88 UID0-2 UID3-6 10字节: 88 UID0-2 88 UID3-5 UID6-9
+func CascadeUID(uid []byte) ([]byte, error) {
+	switch len(uid) {
+	case 4:
+		return append([]byte(nil), uid...), nil
+	case 7:
+		return append([]byte{cascadeTag}, uid...), nil
+	case 10:
+		out := append([]byte{cascadeTag}, uid[:3]...)
+		out = append(out, cascadeTag)
+		return append(out, uid[3:]...), nil
+	}
+	return nil, errors.New("uid length must be 4, 7 or 10")
+}
+
+// isCascadeUID 是否为 CascadeUID 的输出格式
+func isCascadeUID(data []byte) bool {
+	switch len(data) {
+	case 4:
+		return true
+	case 8:
+		return data[0] == cascadeTag
+	case 12:
+		return data[0] == cascadeTag && data[4] == cascadeTag
+	}
+	return false
+}
+
+// InListPassiveTarget 识读最多maxTg(1或2)个brTy类型的目标
+// initiatorData:
+// 106 kbps type A: 可选 指定要选择的卡片UID 需要带级联标志 长度为4、8或12字节 参见 CascadeUID
+// 212/424 kbps FeliCa: 轮询命令载荷 5字节 为空时使用系统码FFFF
+// 106 kbps type B: AFI 为空时使用0x00(所有应用) 可再附加一字节的轮询方式
+// 106 kbps Jewel: 无
+func (p *Pn532) InListPassiveTarget(maxTg, brTy byte, initiatorData ...byte) ([]Target, error) {
+	if maxTg < 0x01 || maxTg > 0x02 {
+		return nil, errors.New("max target number must be 1 or 2")
+	}
+	switch brTy {
+	case ISO14443A:
+		if len(initiatorData) != 0 && !isCascadeUID(initiatorData) {
+			return nil, errors.New("uid must be 4, 8 or 12 bytes with cascade tags, see CascadeUID")
+		}
+	case FeliCa212, FeliCa424:
+		if len(initiatorData) == 0 {
+			initiatorData = feliCaDefaultPolling
+		}
+		if len(initiatorData) != 5 {
+			return nil, errors.New("FeliCa polling payload length must be 5")
+		}
+	case ISO14443B:
+		if len(initiatorData) == 0 {
+			initiatorData = []byte{0x00}
+		}
+		if len(initiatorData) > 2 {
+			return nil, errors.New("type B initiator data must be AFI and optional polling method")
+		}
+	case Jewel:
+		if maxTg != 0x01 {
+			return nil, errors.New("only one Jewel target can be listed at a time")
+		}
+		if len(initiatorData) != 0 {
+			return nil, errors.New("Jewel does not accept initiator data")
+		}
+	default:
+		return nil, errors.New("unknown baud rate and modulation type")
+	}
+
+	resp, err := p.execute(append([]byte{command.InListPassiveTarget, maxTg, brTy}, initiatorData...))
+	if err != nil {
+		return nil, err
+	}
+	if len(resp) == 0 {
+		return nil, errors.New("command resp error")
+	}
+	targets := make([]Target, 0, resp[0])
+	data := resp[1:]
+	for i := 0; i < int(resp[0]); i++ {
+		t, n, err := decodeTargetData(brTy, data, !p.noAutoRATS)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, t)
+		data = data[n:]
+	}
+	p.trackTargets(targets)
+	return targets, nil
+}
+
+// decodeTargetData 解析以Tg开头的TargetData 返回目标与消耗的字节数
+// withATS 表示PN532是否开启了AutoRATS 开启时兼容ISO/IEC14443-4的type A目标会带有ATS
+func decodeTargetData(brTy byte, data []byte, withATS bool) (Target, int, error) {
+	if len(data) < 1 {
+		return nil, 0, errors.New("target data too short")
+	}
+	base := targetBase{tg: data[0], brTy: brTy}
+	switch brTy {
+	case ISO14443A:
+		// Tg SENS_RES(2) SEL_RES NFCIDLength NFCID1 [ATS]
+		if len(data) < 5 {
+			return nil, 0, errors.New("type A target data too short")
+		}
+		uidLen := int(data[4])
+		if uidLen != 4 && uidLen != 7 && uidLen != 10 {
+			return nil, 0, fmt.Errorf("found card with unexpected uid length %d", uidLen)
+		}
+		if len(data) < 5+uidLen {
+			return nil, 0, errors.New("type A target data too short")
+		}
+		t := &TargetA{targetBase: base, ATQA: [2]byte{data[1], data[2]}, SAK: data[3]}
+		t.UID = append([]byte(nil), data[5:5+uidLen]...)
+		n := 5 + uidLen
+		if withATS && t.ISO14443_4() && len(data) > n {
+			atsLen := int(data[n])
+			if atsLen < 1 || len(data) < n+atsLen {
+				return nil, 0, errors.New("invalid ATS length")
+			}
+			t.RawATS = append([]byte(nil), data[n:n+atsLen]...)
+			t.ATS, _ = ParseATS(t.RawATS)
+			n += atsLen
+		}
+		return t, n, nil
+	case FeliCa212, FeliCa424:
+		// Tg POL_RES_LEN 0x01 NFCID2t(8) Pad(8) [SYST_CODE(2)]
+		if len(data) < 2 {
+			return nil, 0, errors.New("FeliCa target data too short")
+		}
+		polLen := int(data[1]) // 包含长度字节本身
+		if (polLen != 18 && polLen != 20) || len(data) < 1+polLen {
+			return nil, 0, errors.New("invalid FeliCa POL_RES length")
+		}
+		t := &TargetFeliCa{targetBase: base, ResponseCode: data[2]}
+		copy(t.IDm[:], data[3:11])
+		copy(t.PMm[:], data[11:19])
+		if polLen == 20 {
+			t.SystemCode = append([]byte(nil), data[19:21]...)
+		}
+		return t, 1 + polLen, nil
+	case ISO14443B:
+		// Tg ATQB_RES(12) ATTRIB_RES_LEN ATTRIB_RES
+		if len(data) < 14 {
+			return nil, 0, errors.New("type B target data too short")
+		}
+		t := &TargetB{targetBase: base}
+		copy(t.ATQB[:], data[1:13])
+		attribLen := int(data[13])
+		if len(data) < 14+attribLen {
+			return nil, 0, errors.New("invalid ATTRIB_RES length")
+		}
+		t.ATTRIBRes = append([]byte(nil), data[14:14+attribLen]...)
+		return t, 14 + attribLen, nil
+	case Jewel:
+		// Tg SENS_RES(2) JEWELID(4)
+		if len(data) < 7 {
+			return nil, 0, errors.New("Jewel target data too short")
+		}
+		t := &TargetJewel{targetBase: base}
+		copy(t.SENSRes[:], data[1:3])
+		copy(t.JewelID[:], data[3:7])
+		return t, 7, nil
+	default:
+		return nil, 0, errors.New("unknown baud rate and modulation type")
+	}
+}
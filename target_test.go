@@ -0,0 +1,109 @@
+package pn532
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDecodeTargetDataA(t *testing.T) {
+	// DESFire EV1: Tg SENS_RES SEL_RES NFCIDLength NFCID1 ATS
+	data := []byte{
+		0x01, 0x03, 0x44, 0x20, 0x07, 0x04, 0x4F, 0x6A, 0x62, 0x9A, 0x22, 0x80,
+		0x06, 0x75, 0x77, 0x81, 0x02, 0x80,
+	}
+	target, n, err := decodeTargetData(ISO14443A, data, true)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if n != len(data) {
+		t.Errorf("unexpected consumed length: %d", n)
+	}
+	a, ok := target.(*TargetA)
+	if !ok {
+		t.Errorf("unexpected target type: %T", target)
+		return
+	}
+	if a.Tg() != 0x01 || a.SAK != 0x20 || a.ATQA != [2]byte{0x03, 0x44} {
+		t.Errorf("unexpected target: %#v", a)
+	}
+	if !bytes.Equal(a.ID(), []byte{0x04, 0x4F, 0x6A, 0x62, 0x9A, 0x22, 0x80}) {
+		t.Errorf("unexpected uid: % X", a.ID())
+	}
+	if !bytes.Equal(a.RawATS, []byte{0x06, 0x75, 0x77, 0x81, 0x02, 0x80}) {
+		t.Errorf("unexpected ats: % X", a.RawATS)
+	}
+	if !a.ISO14443_4() || a.ATS == nil || a.ATS.FSC() != 64 {
+		t.Errorf("unexpected decoded ats: %v", a.ATS)
+	}
+
+	// 关闭AutoRATS时 后续字节属于下一个目标
+	_, n, err = decodeTargetData(ISO14443A, data, false)
+	if err != nil || n != 12 {
+		t.Errorf("unexpected result without ATS: %d %v", n, err)
+	}
+}
+
+func TestCascadeUID(t *testing.T) {
+	for _, c := range []struct {
+		uid, want string
+	}{
+		{"DEADBEEF", "DEADBEEF"},
+		{"04112233445566", "8804112233445566"},
+		{"04112233445566778899", "880411228833445566778899"},
+	} {
+		got, err := CascadeUID(mustHex(c.uid))
+		if err != nil || !bytes.Equal(got, mustHex(c.want)) || !isCascadeUID(got) {
+			t.Errorf("%s: unexpected cascade uid: % X %v", c.uid, got, err)
+		}
+	}
+	if _, err := CascadeUID(make([]byte, 8)); err == nil {
+		t.Error("8-byte uid accepted")
+	}
+	if isCascadeUID(mustHex("04112233445566")) || isCascadeUID(mustHex("0411223344556677")) {
+		t.Error("uid without cascade tag accepted")
+	}
+}
+
+func TestDecodeTargetDataFeliCa(t *testing.T) {
+	data := []byte{
+		0x01, 0x14, 0x01,
+		0x01, 0x2E, 0x4C, 0xA5, 0x36, 0x12, 0x34, 0x56,
+		0x03, 0x32, 0x42, 0x82, 0x82, 0x47, 0xAA, 0xFF,
+		0x88, 0xB4,
+	}
+	target, n, err := decodeTargetData(FeliCa212, data, true)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	f := target.(*TargetFeliCa)
+	if n != len(data) || f.IDm[0] != 0x01 || f.PMm[7] != 0xFF || !bytes.Equal(f.SystemCode, []byte{0x88, 0xB4}) {
+		t.Errorf("unexpected target: %#v", f)
+	}
+}
+
+func TestDecodeTargetDataBAndJewel(t *testing.T) {
+	b := []byte{
+		0x01, 0x50, 0x11, 0x22, 0x33, 0x44, 0x00, 0x00, 0x00, 0x00, 0x00, 0x81, 0x81,
+		0x01, 0x00,
+	}
+	target, n, err := decodeTargetData(ISO14443B, b, true)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if n != len(b) || !bytes.Equal(target.ID(), []byte{0x11, 0x22, 0x33, 0x44}) {
+		t.Errorf("unexpected type B target: %#v", target)
+	}
+
+	j := []byte{0x01, 0x0C, 0x00, 0xAA, 0xBB, 0xCC, 0xDD}
+	target, n, err = decodeTargetData(Jewel, j, true)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if n != len(j) || !bytes.Equal(target.ID(), []byte{0xAA, 0xBB, 0xCC, 0xDD}) {
+		t.Errorf("unexpected Jewel target: %#v", target)
+	}
+}
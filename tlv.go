@@ -0,0 +1,298 @@
+package pn532
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// TLV BER-TLV 数据对象
+// Tag 为按大端排列的原始标签字节 例如 0x6F、0xBF0C、0x9F38
+type TLV struct {
+	Tag      uint32
+	Value    []byte // 基本类型的值 构造类型的值由Children编码得到
+	Children TLVList
+}
+
+// TLVList 按顺序排列的数据对象
+type TLVList []*TLV
+
+// NewTLV 创建基本类型的数据对象
+func NewTLV(tag uint32, value []byte) *TLV {
+	return &TLV{Tag: tag, Value: value}
+}
+
+// NewConstructedTLV 创建构造类型的数据对象
+func NewConstructedTLV(tag uint32, children ...*TLV) *TLV {
+	return &TLV{Tag: tag, Children: children}
+}
+
+// tagBytes 标签的原始字节
+func tagBytes(tag uint32) []byte {
+	var b []byte
+	for shift := 24; shift > 0; shift -= 8 {
+		if v := byte(tag >> uint(shift)); v != 0 || len(b) > 0 {
+			b = append(b, v)
+		}
+	}
+	return append(b, byte(tag))
+}
+
+// Constructed 标签第一个字节的bit6表示是否为构造类型
+func (t *TLV) Constructed() bool {
+	return tagBytes(t.Tag)[0]&0x20 != 0
+}
+
+// Find 在子对象中按路径查找 路径为以/分隔的十六进制标签 例如 "A5/BF0C/61/4F"
+func (t *TLV) Find(path string) *TLV {
+	return t.Children.Find(path)
+}
+
+// Bytes 编码数据对象
+func (t *TLV) Bytes() []byte {
+	value := t.Value
+	if t.Constructed() && t.Children != nil {
+		value = t.Children.Bytes()
+	}
+	buf := tagBytes(t.Tag)
+	buf = append(buf, encodeTLVLength(len(value))...)
+	return append(buf, value...)
+}
+
+func (t *TLV) String() string {
+	var b strings.Builder
+	t.format(&b, 0)
+	return b.String()
+}
+
+func (t *TLV) format(b *strings.Builder, depth int) {
+	b.WriteString(strings.Repeat("  ", depth))
+	fmt.Fprintf(b, "%X", t.Tag)
+	if name, ok := TLVTagNames[t.Tag]; ok {
+		fmt.Fprintf(b, " (%s)", name)
+	}
+	if t.Constructed() {
+		b.WriteString("\n")
+		for _, child := range t.Children {
+			child.format(b, depth+1)
+		}
+		return
+	}
+	fmt.Fprintf(b, ": % X", t.Value)
+	if isPrintable(t.Value) {
+		fmt.Fprintf(b, " %q", t.Value)
+	}
+	b.WriteString("\n")
+}
+
+func isPrintable(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+	for _, c := range data {
+		if c < 0x20 || c > 0x7E {
+			return false
+		}
+	}
+	return true
+}
+
+func encodeTLVLength(n int) []byte {
+	switch {
+	case n < 0x80:
+		return []byte{byte(n)}
+	case n <= 0xFF:
+		return []byte{0x81, byte(n)}
+	case n <= 0xFFFF:
+		return []byte{0x82, byte(n >> 8), byte(n)}
+	case n <= 0xFFFFFF:
+		return []byte{0x83, byte(n >> 16), byte(n >> 8), byte(n)}
+	default:
+		return []byte{0x84, byte(n >> 24), byte(n >> 16), byte(n >> 8), byte(n)}
+	}
+}
+
+// Find 按路径查找数据对象 路径为以/分隔的十六进制标签 例如 "6F/A5/BF0C/61/4F"
+// 同一层有多个相同标签时返回第一个 找不到时返回nil
+func (l TLVList) Find(path string) *TLV {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	list := l
+	var found *TLV
+	for _, part := range parts {
+		tag, err := strconv.ParseUint(part, 16, 32)
+		if err != nil {
+			return nil
+		}
+		found = nil
+		for _, t := range list {
+			if t.Tag == uint32(tag) {
+				found = t
+				break
+			}
+		}
+		if found == nil {
+			return nil
+		}
+		list = found.Children
+	}
+	return found
+}
+
+// Bytes 依次编码所有数据对象
+func (l TLVList) Bytes() []byte {
+	var buf []byte
+	for _, t := range l {
+		buf = append(buf, t.Bytes()...)
+	}
+	return buf
+}
+
+func (l TLVList) String() string {
+	var b strings.Builder
+	for _, t := range l {
+		t.format(&b, 0)
+	}
+	return b.String()
+}
+
+// ParseTLV 解析BER-TLV编码的数据 构造类型的数据对象会递归解析
+// 数据对象之间的0x00与0xFF填充字节会被忽略
+func ParseTLV(data []byte) (TLVList, error) {
+	var list TLVList
+	for len(data) > 0 {
+		if data[0] == 0x00 || data[0] == 0xFF {
+			data = data[1:]
+			continue
+		}
+		t, n, err := parseOneTLV(data)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, t)
+		data = data[n:]
+	}
+	return list, nil
+}
+
+func parseOneTLV(data []byte) (*TLV, int, error) {
+	// 标签
+	i := 1
+	tag := uint32(data[0])
+	if data[0]&0x1F == 0x1F {
+		for {
+			if i >= len(data) {
+				return nil, 0, errors.New("tlv: truncated tag")
+			}
+			if i >= 4 {
+				return nil, 0, errors.New("tlv: tag too long")
+			}
+			tag = tag<<8 | uint32(data[i])
+			i++
+			if data[i-1]&0x80 == 0 {
+				break
+			}
+		}
+	}
+	// 长度
+	if i >= len(data) {
+		return nil, 0, errors.New("tlv: missing length")
+	}
+	length := int(data[i])
+	i++
+	if length > 0x80 {
+		n := length & 0x7F
+		if n > 4 {
+			return nil, 0, errors.New("tlv: length too long")
+		}
+		if i+n > len(data) {
+			return nil, 0, errors.New("tlv: truncated length")
+		}
+		length = 0
+		for _, b := range data[i : i+n] {
+			length = length<<8 | int(b)
+		}
+		i += n
+	} else if length == 0x80 {
+		return nil, 0, errors.New("tlv: indefinite length not supported")
+	}
+	if length < 0 || i+length > len(data) {
+		return nil, 0, errors.New("tlv: truncated value")
+	}
+	t := &TLV{Tag: tag, Value: append([]byte(nil), data[i:i+length]...)}
+	if t.Constructed() {
+		children, err := ParseTLV(t.Value)
+		if err != nil {
+			return nil, 0, fmt.Errorf("tlv %X: %w", tag, err)
+		}
+		t.Children = children
+		if t.Children == nil {
+			t.Children = TLVList{}
+		}
+	}
+	return t, i + length, nil
+}
+
+// TLVTagNames 常见的ISO7816与EMV标签名称 用于打印
+var TLVTagNames = map[uint32]string{
+	0x42:   "Issuer Identification Number",
+	0x4F:   "Application Identifier (AID)",
+	0x50:   "Application Label",
+	0x57:   "Track 2 Equivalent Data",
+	0x5A:   "Application PAN",
+	0x5F20: "Cardholder Name",
+	0x5F24: "Application Expiration Date",
+	0x5F25: "Application Effective Date",
+	0x5F28: "Issuer Country Code",
+	0x5F2D: "Language Preference",
+	0x5F34: "Application PAN Sequence Number",
+	0x61:   "Application Template",
+	0x62:   "File Control Parameters (FCP) Template",
+	0x64:   "File Management Data (FMD) Template",
+	0x6F:   "File Control Information (FCI) Template",
+	0x70:   "Record Template",
+	0x77:   "Response Message Template Format 2",
+	0x80:   "Response Message Template Format 1",
+	0x82:   "Application Interchange Profile / File Descriptor",
+	0x83:   "File Identifier",
+	0x84:   "Dedicated File (DF) Name",
+	0x87:   "Application Priority Indicator",
+	0x88:   "Short File Identifier (SFI)",
+	0x8A:   "Life Cycle Status",
+	0x8C:   "CDOL1",
+	0x8D:   "CDOL2",
+	0x8E:   "CVM List",
+	0x8F:   "Certification Authority Public Key Index",
+	0x90:   "Issuer Public Key Certificate",
+	0x92:   "Issuer Public Key Remainder",
+	0x94:   "Application File Locator (AFL)",
+	0x95:   "Terminal Verification Results",
+	0x9A:   "Transaction Date",
+	0x9C:   "Transaction Type",
+	0x9F02: "Amount, Authorised",
+	0x9F07: "Application Usage Control",
+	0x9F08: "Application Version Number",
+	0x9F0D: "Issuer Action Code - Default",
+	0x9F0E: "Issuer Action Code - Denial",
+	0x9F0F: "Issuer Action Code - Online",
+	0x9F10: "Issuer Application Data",
+	0x9F11: "Issuer Code Table Index",
+	0x9F12: "Application Preferred Name",
+	0x9F1A: "Terminal Country Code",
+	0x9F26: "Application Cryptogram",
+	0x9F27: "Cryptogram Information Data",
+	0x9F32: "Issuer Public Key Exponent",
+	0x9F36: "Application Transaction Counter (ATC)",
+	0x9F37: "Unpredictable Number",
+	0x9F38: "PDOL",
+	0x9F42: "Application Currency Code",
+	0x9F44: "Application Currency Exponent",
+	0x9F46: "ICC Public Key Certificate",
+	0x9F47: "ICC Public Key Exponent",
+	0x9F48: "ICC Public Key Remainder",
+	0x9F4A: "Static Data Authentication Tag List",
+	0x9F4D: "Log Entry",
+	0x9F6E: "Form Factor Indicator",
+	0xA5:   "FCI Proprietary Template",
+	0xBF0C: "FCI Issuer Discretionary Data",
+}
@@ -0,0 +1,66 @@
+package pn532
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTLV(t *testing.T) {
+	// PPSE SELECT 的响应
+	ppse := []byte{
+		0x6F, 0x2C,
+		0x84, 0x0E, '2', 'P', 'A', 'Y', '.', 'S', 'Y', 'S', '.', 'D', 'D', 'F', '0', '1',
+		0xA5, 0x1A,
+		0xBF, 0x0C, 0x17,
+		0x61, 0x15,
+		0x4F, 0x07, 0xA0, 0x00, 0x00, 0x00, 0x04, 0x10, 0x10,
+		0x50, 0x0A, 'M', 'a', 's', 't', 'e', 'r', 'C', 'a', 'r', 'd',
+	}
+	list, err := ParseTLV(ppse)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aid := list.Find("6F/A5/BF0C/61/4F")
+	if aid == nil || !bytes.Equal(aid.Value, []byte{0xA0, 0x00, 0x00, 0x00, 0x04, 0x10, 0x10}) {
+		t.Fatalf("unexpected AID: %v", aid)
+	}
+	if list.Find("6F/A5/61") != nil || list.Find("zz") != nil {
+		t.Error("unexpected match")
+	}
+	if !list[0].Constructed() || aid.Constructed() {
+		t.Error("wrong constructed flag")
+	}
+	if !bytes.Equal(list.Bytes(), ppse) {
+		t.Errorf("unexpected encoding: % X", list.Bytes())
+	}
+	if s := list.String(); !strings.Contains(s, "      4F (Application Identifier (AID)): A0 00 00 00 04 10 10\n") {
+		t.Errorf("unexpected output:\n%s", s)
+	}
+}
+
+func TestTLVLength(t *testing.T) {
+	value := make([]byte, 300)
+	encoded := NewConstructedTLV(0x70, NewTLV(0x9F46, value)).Bytes()
+	if !bytes.Equal(encoded[:7], []byte{0x70, 0x82, 0x01, 0x31, 0x9F, 0x46, 0x82}) {
+		t.Errorf("unexpected header: % X", encoded[:7])
+	}
+	list, err := ParseTLV(append([]byte{0x00, 0x00}, encoded...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := list.Find("70/9F46"); v == nil || len(v.Value) != 300 {
+		t.Error("long form length not decoded")
+	}
+	for _, bad := range [][]byte{
+		{0x9F},
+		{0x5A, 0x05, 0x01},
+		{0x5A, 0x80},
+		{0x5A, 0x82, 0x01},
+		{0x6F, 0x02, 0x84, 0x05},
+	} {
+		if _, err := ParseTLV(bad); err == nil {
+			t.Errorf("% X accepted", bad)
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package pn532
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// MIFARE Ultralight / NTAG21x 命令
+const (
+	ulCmdGetVersion        byte = 0x60
+	ulCmdRead              byte = 0x30
+	ulCmdFastRead          byte = 0x3A
+	ulCmdWrite             byte = 0xA2
+	ulCmdCompatWrite       byte = 0xA0
+	ulCmdReadCnt           byte = 0x39
+	ulCmdIncrCnt           byte = 0xA5
+	ulCmdPwdAuth           byte = 0x1B
+	ulCmdReadSig           byte = 0x3C
+	ulCmdCheckTearingEvent byte = 0x3E
+	ulCmdAuthenticate      byte = 0x1A // Ultralight C
+)
+
+// ulACK 4位的ACK
+const ulACK byte = 0x0A
+
+// NAKError 卡片返回的4位NAK 之后卡片会回到IDLE状态
+type NAKError byte
+
+const (
+	NAKInvalidArgument     NAKError = 0x00 // 无效的参数 例如页号超出范围或没有权限
+	NAKCRC                 NAKError = 0x01 // CRC或奇偶校验错误
+	NAKAuthCounterOverflow NAKError = 0x04 // 认证失败次数超过限制
+	NAKEEPROMWrite         NAKError = 0x05 // EEPROM写入错误
+)
+
+func (e NAKError) Error() string {
+	switch e {
+	case NAKInvalidArgument:
+		return "NAK: invalid argument"
+	case NAKCRC:
+		return "NAK: parity or CRC error"
+	case NAKAuthCounterOverflow:
+		return "NAK: authentication counter overflow"
+	case NAKEEPROMWrite:
+		return "NAK: EEPROM write error"
+	}
+	return fmt.Sprintf("NAK: %#X", byte(e))
+}
+
+// ErrPACKMismatch PWD_AUTH 返回的PACK与期望的不同 卡片可能是伪造的
+var ErrPACKMismatch = errors.New("PACK mismatch")
+
+// fastReadMaxPages 每次FAST_READ最多读取的页数 受PN532帧长度限制
+const fastReadMaxPages = 60
+
+// Ultralight MIFARE Ultralight / NTAG21x 卡片
+type Ultralight struct {
+	Version *VersionInfo // GET_VERSION的结果 不支持时为nil
+	Pages   int          // 总页数 包括配置页
+
+	transceive    func([]byte) ([]byte, error) // 原始交换 响应中包含CRC
+	reactivate    func() error
+	authenticated bool // PWD_AUTH成功 卡片回到IDLE后失效
+}
+
+// Ultralight 在当前选择的目标上打开Ultralight/NTAG命令集 会发送GET_VERSION确定容量
+func (p *Pn532) Ultralight(t *TargetA) (*Ultralight, error) {
+	if t == nil || p.current != Target(t) || t.State() != TargetSelected {
+		return nil, ErrNoTarget
+	}
+	u := &Ultralight{
+		transceive: func(data []byte) ([]byte, error) {
+			return p.CommunicateThru(data, &ThruOptions{NoRxCRC: true})
+		},
+		reactivate: func() error {
+			return p.Reactivate(t)
+		},
+	}
+	if err := u.detect(); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// detect 根据GET_VERSION确定页数 不支持GET_VERSION时通过AUTHENTICATE区分Ultralight C
+func (u *Ultralight) detect() error {
+	v, err := u.GetVersion()
+	if err != nil {
+		if !isStatusError(err) && !isNAK(err) {
+			return err
+		}
+		u.Pages = 16
+		resp, err := u.exchange([]byte{ulCmdAuthenticate, 0x00})
+		if err != nil {
+			if isStatusError(err) || isNAK(err) {
+				return nil
+			}
+			return err
+		}
+		if len(resp) == 9 && resp[0] == 0xAF {
+			u.Pages = 48
+		}
+		// 中止认证
+		if u.reactivate != nil {
+			return u.reactivate()
+		}
+		return nil
+	}
+	u.Version = v
+	u.Pages = ultralightPages(v)
+	return nil
+}
+
+// ultralightPages 根据GET_VERSION确定总页数
+func ultralightPages(v *VersionInfo) int {
+	switch {
+	case v.ProductType == 0x03 && v.StorageSize == 0x0B: // MF0UL11
+		return 20
+	case v.ProductType == 0x03 && v.StorageSize == 0x0E: // MF0UL21
+		return 41
+	case v.ProductType == 0x04 && v.StorageSize == 0x0F: // NTAG213
+		return 45
+	case v.ProductType == 0x04 && v.StorageSize == 0x11: // NTAG215
+		return 135
+	case v.ProductType == 0x04 && v.StorageSize == 0x13: // NTAG216
+		return 231
+	}
+	// 其他型号按容量估算 用户区之外还有4页头部与约5页配置
+	return (1<<(v.StorageSize>>1))/4 + 9
+}
+
+func isNAK(err error) bool {
+	var nak NAKError
+	return errors.As(err, &nak)
+}
+
+// exchange 发送命令 返回去除CRC后的响应 4位的响应按ACK/NAK处理
+// 出现NAK或者卡片没有响应时卡片已回到IDLE状态 会自动重新激活
+func (u *Ultralight) exchange(cmd []byte) ([]byte, error) {
+	resp, err := u.transceive(cmd)
+	if err == nil {
+		resp, err = checkUltralightResp(resp)
+	}
+	if err != nil && (isNAK(err) || isStatusError(err)) && u.reactivate != nil {
+		u.authenticated = false
+		if reErr := u.reactivate(); reErr != nil {
+			return nil, reErr
+		}
+	}
+	return resp, err
+}
+
+func checkUltralightResp(resp []byte) ([]byte, error) {
+	if len(resp) == 1 {
+		if resp[0]&0x0F == ulACK {
+			return nil, nil
+		}
+		return nil, NAKError(resp[0] & 0x0F)
+	}
+	if !CheckCRCA(resp) {
+		return nil, StatusCRC
+	}
+	return resp[:len(resp)-2], nil
+}
+
+// ack 发送只返回ACK的命令
+func (u *Ultralight) ack(cmd []byte) error {
+	resp, err := u.exchange(cmd)
+	if err != nil {
+		return err
+	}
+	if resp != nil {
+		return errors.New("unexpected response, expected ACK")
+	}
+	return nil
+}
+
+// data 发送返回n字节数据的命令
+func (u *Ultralight) data(cmd []byte, n int) ([]byte, error) {
+	resp, err := u.exchange(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp) != n {
+		return nil, fmt.Errorf("unexpected response length %d, expected %d", len(resp), n)
+	}
+	return resp, nil
+}
+
+// GetVersion 读取产品信息
+func (u *Ultralight) GetVersion() (*VersionInfo, error) {
+	resp, err := u.data([]byte{ulCmdGetVersion}, 8)
+	if err != nil {
+		return nil, err
+	}
+	return parseVersionInfo(resp[1:])
+}
+
+// Read 从page开始读取4页(16字节) 超出末尾时会从第0页回绕
+func (u *Ultralight) Read(page byte) ([]byte, error) {
+	return u.data([]byte{ulCmdRead, page}, 16)
+}
+
+// FastRead 读取从start到end(包括end)的所有页 超过一帧时自动分段
+func (u *Ultralight) FastRead(start, end byte) ([]byte, error) {
+	if end < start {
+		return nil, errors.New("end page must not be less than start page")
+	}
+	var out []byte
+	for page := int(start); page <= int(end); page += fastReadMaxPages {
+		last := page + fastReadMaxPages - 1
+		if last > int(end) {
+			last = int(end)
+		}
+		resp, err := u.data([]byte{ulCmdFastRead, byte(page), byte(last)}, (last-page+1)*4)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, resp...)
+	}
+	return out, nil
+}
+
+// Write 写入一页(4字节)
+func (u *Ultralight) Write(page byte, data []byte) error {
+	if len(data) != 4 {
+		return errors.New("data length must be 4")
+	}
+	return u.ack(append([]byte{ulCmdWrite, page}, data...))
+}
+
+// CompatWrite 兼容MIFARE Classic的写入 发送16字节 只有前4字节写入page
+func (u *Ultralight) CompatWrite(page byte, data []byte) error {
+	if len(data) != 16 {
+		return errors.New("data length must be 16")
+	}
+	if err := u.ack([]byte{ulCmdCompatWrite, page}); err != nil {
+		return err
+	}
+	return u.ack(data)
+}
+
+// ReadCnt 读取单调计数器 NTAG21x只有计数器2可读
+func (u *Ultralight) ReadCnt(counter byte) (uint32, error) {
+	resp, err := u.data([]byte{ulCmdReadCnt, counter}, 3)
+	if err != nil {
+		return 0, err
+	}
+	return uint32(resp[0]) | uint32(resp[1])<<8 | uint32(resp[2])<<16, nil
+}
+
+// IncrCnt 将单调计数器增加inc 计数器为24位
+func (u *Ultralight) IncrCnt(counter byte, inc uint32) error {
+	if inc > 0xFFFFFF {
+		return errors.New("increment must fit in 24 bits")
+	}
+	return u.ack([]byte{ulCmdIncrCnt, counter, byte(inc), byte(inc >> 8), byte(inc >> 16), 0x00})
+}
+
+// PwdAuth 使用32位密码认证 返回卡片的PACK expectedPACK不为nil时会校验PACK
+func (u *Ultralight) PwdAuth(pwd []byte, expectedPACK []byte) ([]byte, error) {
+	if len(pwd) != 4 {
+		return nil, errors.New("password length must be 4")
+	}
+	pack, err := u.data(append([]byte{ulCmdPwdAuth}, pwd...), 2)
+	if err != nil {
+		return nil, err
+	}
+	if expectedPACK != nil && !bytes.Equal(pack, expectedPACK) {
+		return pack, ErrPACKMismatch
+	}
+	u.authenticated = true
+	return pack, nil
+}
+
+// ReadSig 读取32字节的原厂ECC签名
+func (u *Ultralight) ReadSig() ([]byte, error) {
+	return u.data([]byte{ulCmdReadSig, 0x00}, 32)
+}
+
+// CheckTearingEvent 检查计数器最近一次增加是否完整 返回true表示没有发生撕裂
+func (u *Ultralight) CheckTearingEvent(counter byte) (bool, error) {
+	resp, err := u.data([]byte{ulCmdCheckTearingEvent, counter}, 1)
+	if err != nil {
+		return false, err
+	}
+	return resp[0] == 0xBD, nil
+}
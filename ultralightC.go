@@ -0,0 +1,122 @@
+package pn532
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+)
+
+// MIFARE Ultralight C 的配置页
+const (
+	ulcPageAuth0 byte = 0x2A // 第0字节为AUTH0 从此页开始需要认证 0x30表示不启用
+	ulcPageAuth1 byte = 0x2B // 第0字节的最低位为AUTH1 1: 只限制写入 0: 读写都需要认证
+	ulcPageKey   byte = 0x2C // 0x2C-0x2F 2K3DES密钥 只能写入
+)
+
+// UltralightCDefaultKey 出厂密钥 "BREAKMEIFYOUCAN!"
+var UltralightCDefaultKey = []byte("BREAKMEIFYOUCAN!")
+
+// ErrCardAuthentication 卡片对挑战的回应不正确
+var ErrCardAuthentication = errors.New("card authentication failed")
+
+// rotateLeft 循环左移一个字节
+func rotateLeft(b []byte) []byte {
+	return append(append([]byte(nil), b[1:]...), b[0])
+}
+
+// UltralightCAuthenticate 使用16字节的2K3DES密钥进行双向认证
+// 第一步 PCD: 1A 00 PICC: AF || ek(RndB)
+// 第二步 PCD: AF || ek(RndA || RndB') PICC: 00 || ek(RndA')
+// 使用CBC模式 每一步的IV为上一条密文的最后一个分组 RndB'与RndA'为循环左移一个字节
+// 密钥错误时卡片返回NAK并回到IDLE状态 此时会自动重新激活
+func (u *Ultralight) UltralightCAuthenticate(key []byte) error {
+	if len(key) != 16 {
+		return errors.New("key length must be 16")
+	}
+	block, err := newTDESCipher(key)
+	if err != nil {
+		return err
+	}
+	resp, err := u.exchange([]byte{ulCmdAuthenticate, 0x00})
+	if err != nil {
+		return err
+	}
+	if len(resp) != 9 || resp[0] != 0xAF {
+		return errors.New("unexpected AUTHENTICATE response")
+	}
+	encRndB := resp[1:]
+	rndB := make([]byte, 8)
+	cipher.NewCBCDecrypter(block, make([]byte, 8)).CryptBlocks(rndB, encRndB)
+
+	rndA := make([]byte, 8)
+	if _, err := rand.Read(rndA); err != nil {
+		return err
+	}
+	challenge := append(append([]byte(nil), rndA...), rotateLeft(rndB)...)
+	cipher.NewCBCEncrypter(block, encRndB).CryptBlocks(challenge, challenge)
+
+	resp, err = u.exchange(append([]byte{0xAF}, challenge...))
+	if err != nil {
+		return err
+	}
+	if len(resp) != 9 || resp[0] != 0x00 {
+		return errors.New("unexpected AUTHENTICATE response")
+	}
+	rndA2 := make([]byte, 8)
+	cipher.NewCBCDecrypter(block, challenge[8:]).CryptBlocks(rndA2, resp[1:])
+	if !bytes.Equal(rndA2, rotateLeft(rndA)) {
+		// 卡片没有正确解密RndA 可能是仿制的卡片
+		if u.reactivate != nil {
+			if err := u.reactivate(); err != nil {
+				return err
+			}
+		}
+		return ErrCardAuthentication
+	}
+	return nil
+}
+
+// WriteUltralightCKey 写入新的2K3DES密钥 密钥区无法读出 写入后应使用新密钥重新认证确认
+// 两个8字节的半密钥分别按字节倒序写入 0x2C-0x2D为K1 0x2E-0x2F为K2
+func (u *Ultralight) WriteUltralightCKey(key []byte) error {
+	if len(key) != 16 {
+		return errors.New("key length must be 16")
+	}
+	data := make([]byte, 16)
+	for i := 0; i < 8; i++ {
+		data[i] = key[7-i]
+		data[8+i] = key[15-i]
+	}
+	for i := 0; i < 4; i++ {
+		if err := u.Write(ulcPageKey+byte(i), data[i*4:i*4+4]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UltralightCAuthConfig 读取AUTH0与AUTH1 writeOnly为true时只有写入需要认证
+func (u *Ultralight) UltralightCAuthConfig() (auth0 byte, writeOnly bool, err error) {
+	data, err := u.Read(ulcPageAuth0)
+	if err != nil {
+		return 0, false, err
+	}
+	return data[0], data[4]&0x01 != 0, nil
+}
+
+// SetUltralightCAuth 设置从auth0开始的页需要认证 auth0为0x03-0x30 0x30表示不启用
+// 先写入AUTH1再写入AUTH0 避免保护范围生效时访问方式还未确定
+func (u *Ultralight) SetUltralightCAuth(auth0 byte, writeOnly bool) error {
+	if auth0 < 0x03 || auth0 > 0x30 {
+		return errors.New("AUTH0 must be 0x03-0x30")
+	}
+	auth1 := byte(0x00)
+	if writeOnly {
+		auth1 = 0x01
+	}
+	if err := u.Write(ulcPageAuth1, []byte{auth1, 0x00, 0x00, 0x00}); err != nil {
+		return err
+	}
+	return u.Write(ulcPageAuth0, []byte{auth0, 0x00, 0x00, 0x00})
+}
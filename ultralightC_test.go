@@ -0,0 +1,165 @@
+package pn532
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"testing"
+)
+
+// fakeUltralightC 模拟Ultralight C 不支持GET_VERSION
+type fakeUltralightC struct {
+	mem      []byte
+	rndB     []byte
+	iv       []byte // 上一条密文的最后一个分组 为nil时不在认证过程中
+	authed   bool
+	badRndA  bool // 返回错误的ek(RndA') 模拟没有正确密钥的仿制卡片
+	reactive int
+}
+
+func newFakeUltralightC() *fakeUltralightC {
+	f := &fakeUltralightC{mem: make([]byte, 48*4)}
+	copy(f.mem[0x2A*4:], []byte{0x30, 0x00, 0x00, 0x00})
+	f.setKey(UltralightCDefaultKey)
+	return f
+}
+
+// setKey 按照卡片的存储格式写入密钥
+func (f *fakeUltralightC) setKey(key []byte) {
+	for i := 0; i < 8; i++ {
+		f.mem[0x2C*4+i] = key[7-i]
+		f.mem[0x2C*4+8+i] = key[15-i]
+	}
+}
+
+func (f *fakeUltralightC) key() []byte {
+	key := make([]byte, 16)
+	for i := 0; i < 8; i++ {
+		key[7-i] = f.mem[0x2C*4+i]
+		key[15-i] = f.mem[0x2C*4+8+i]
+	}
+	return key
+}
+
+func (f *fakeUltralightC) transceive(cmd []byte) ([]byte, error) {
+	nak := []byte{byte(NAKInvalidArgument)}
+	block, _ := newTDESCipher(f.key())
+	switch cmd[0] {
+	case ulCmdAuthenticate:
+		f.rndB = []byte{0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88}
+		enc := make([]byte, 8)
+		cipher.NewCBCEncrypter(block, make([]byte, 8)).CryptBlocks(enc, f.rndB)
+		f.iv = enc
+		return AppendCRCA(append([]byte{0xAF}, enc...)), nil
+	case 0xAF:
+		if f.iv == nil || len(cmd) != 17 {
+			return nak, nil
+		}
+		plain := make([]byte, 16)
+		cipher.NewCBCDecrypter(block, f.iv).CryptBlocks(plain, cmd[1:])
+		f.iv = nil
+		if !bytes.Equal(plain[8:], rotateLeft(f.rndB)) {
+			return nak, nil
+		}
+		f.authed = true
+		rndA := rotateLeft(plain[:8])
+		if f.badRndA {
+			rndA[0] ^= 0xFF
+		}
+		enc := make([]byte, 8)
+		cipher.NewCBCEncrypter(block, cmd[9:]).CryptBlocks(enc, rndA)
+		return AppendCRCA(append([]byte{0x00}, enc...)), nil
+	case ulCmdRead:
+		if cmd[1] >= 0x2C {
+			return nak, nil
+		}
+		return AppendCRCA(append([]byte(nil), f.mem[int(cmd[1])*4:int(cmd[1])*4+16]...)), nil
+	case ulCmdWrite:
+		if cmd[1] >= f.mem[0x2A*4] && !f.authed {
+			return nak, nil
+		}
+		copy(f.mem[int(cmd[1])*4:], cmd[2:6])
+		return []byte{ulACK}, nil
+	}
+	return nak, nil
+}
+
+func (f *fakeUltralightC) ultralight() *Ultralight {
+	return &Ultralight{
+		transceive: f.transceive,
+		reactivate: func() error {
+			f.reactive++
+			f.authed, f.iv = false, nil
+			return nil
+		},
+	}
+}
+
+func TestUltralightCDetect(t *testing.T) {
+	tag := newFakeUltralightC()
+	u := tag.ultralight()
+	if err := u.detect(); err != nil {
+		t.Fatal(err)
+	}
+	if u.Pages != 48 || u.Version != nil {
+		t.Errorf("unexpected pages: %d", u.Pages)
+	}
+	// GET_VERSION的NAK与中止认证各重新激活一次
+	if tag.reactive != 2 {
+		t.Errorf("reactivated %d times", tag.reactive)
+	}
+}
+
+func TestUltralightCAuthenticate(t *testing.T) {
+	tag := newFakeUltralightC()
+	u := tag.ultralight()
+	if err := u.UltralightCAuthenticate(UltralightCDefaultKey); err != nil || !tag.authed {
+		t.Fatalf("authentication failed: %v", err)
+	}
+	wrong := append([]byte(nil), UltralightCDefaultKey...)
+	wrong[0] ^= 0x02 // 最低位是DES的校验位 不影响加密
+	if err := u.UltralightCAuthenticate(wrong); err != NAKInvalidArgument || tag.authed || tag.reactive != 1 {
+		t.Errorf("wrong key accepted: %v", err)
+	}
+	if err := u.UltralightCAuthenticate(wrong[:8]); err == nil {
+		t.Error("short key accepted")
+	}
+
+	// 卡片接受了挑战 但回应的RndA'不正确
+	tag.badRndA = true
+	if err := u.UltralightCAuthenticate(UltralightCDefaultKey); err != ErrCardAuthentication {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if tag.authed || tag.reactive != 2 {
+		t.Errorf("card not reactivated: authed %t reactivated %d times", tag.authed, tag.reactive)
+	}
+}
+
+func TestUltralightCKey(t *testing.T) {
+	tag := newFakeUltralightC()
+	// 出厂密钥在存储器中的格式
+	if !bytes.Equal(tag.mem[0x2C*4:], mustHex("49454D4B41455242214E4143554F5946")) {
+		t.Fatalf("unexpected key layout: % X", tag.mem[0x2C*4:])
+	}
+	u := tag.ultralight()
+	key := mustHex("00112233445566778899AABBCCDDEEFF")
+	if err := u.WriteUltralightCKey(key); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(tag.mem[0x2C*4:], mustHex("7766554433221100FFEEDDCCBBAA9988")) {
+		t.Errorf("unexpected key pages: % X", tag.mem[0x2C*4:])
+	}
+	if err := u.UltralightCAuthenticate(key); err != nil {
+		t.Errorf("new key rejected: %v", err)
+	}
+
+	if err := u.SetUltralightCAuth(0x10, true); err != nil {
+		t.Fatal(err)
+	}
+	auth0, writeOnly, err := u.UltralightCAuthConfig()
+	if err != nil || auth0 != 0x10 || !writeOnly {
+		t.Errorf("unexpected auth config: %#X %t %v", auth0, writeOnly, err)
+	}
+	if err := u.SetUltralightCAuth(0x31, false); err == nil {
+		t.Error("invalid AUTH0 accepted")
+	}
+}
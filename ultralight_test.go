@@ -0,0 +1,195 @@
+package pn532
+
+import (
+	"bytes"
+	"testing"
+)
+
+// fakeNTAG 模拟NTAG213 响应附加CRC_A
+type fakeNTAG struct {
+	mem      []byte
+	pwd      []byte
+	pack     []byte
+	counter  uint32
+	compat   int // 等待COMPATIBILITY_WRITE第二阶段的页号 -1表示无
+	authed   bool
+	reactive int
+	writes   []byte // WRITE命令写入的页号
+}
+
+func newFakeNTAG() *fakeNTAG {
+	tag := &fakeNTAG{
+		mem:    make([]byte, 45*4),
+		pwd:    []byte{0xFF, 0xFF, 0xFF, 0xFF},
+		pack:   []byte{0x00, 0x00},
+		compat: -1,
+	}
+	copy(tag.mem, []byte{0x04, 0x11, 0x22, 0xBF, 0x33, 0x44, 0x55, 0x66})
+	for i := range tag.mem[16:] {
+		tag.mem[16+i] = byte(i)
+	}
+	return tag
+}
+
+func (f *fakeNTAG) ultralight() *Ultralight {
+	return &Ultralight{
+		transceive: f.transceive,
+		reactivate: func() error {
+			f.reactive++
+			f.authed = false
+			return nil
+		},
+	}
+}
+
+func (f *fakeNTAG) transceive(cmd []byte) ([]byte, error) {
+	nak := []byte{byte(NAKInvalidArgument)}
+	if f.compat >= 0 {
+		page := f.compat
+		f.compat = -1
+		copy(f.mem[page*4:], cmd[:4])
+		return []byte{ulACK}, nil
+	}
+	pages := len(f.mem) / 4
+	switch cmd[0] {
+	case ulCmdGetVersion:
+		return AppendCRCA([]byte{0x00, 0x04, 0x04, 0x02, 0x01, 0x00, 0x0F, 0x03}), nil
+	case ulCmdRead:
+		if int(cmd[1]) >= pages {
+			return nak, nil
+		}
+		var out []byte
+		for i := 0; i < 4; i++ {
+			page := (int(cmd[1]) + i) % pages
+			out = append(out, f.mem[page*4:page*4+4]...)
+		}
+		return AppendCRCA(out), nil
+	case ulCmdFastRead:
+		if cmd[2] < cmd[1] || int(cmd[2]) >= pages {
+			return nak, nil
+		}
+		return AppendCRCA(append([]byte(nil), f.mem[int(cmd[1])*4:int(cmd[2])*4+4]...)), nil
+	case ulCmdWrite:
+		if cmd[1] < 2 || int(cmd[1]) >= pages {
+			return nak, nil
+		}
+		f.writes = append(f.writes, cmd[1])
+		if cmd[1] == 2 {
+			// 锁定字节按位或写入
+			f.mem[10] |= cmd[4]
+			f.mem[11] |= cmd[5]
+			return []byte{ulACK}, nil
+		}
+		copy(f.mem[int(cmd[1])*4:], cmd[2:6])
+		return []byte{ulACK}, nil
+	case ulCmdCompatWrite:
+		f.compat = int(cmd[1])
+		return []byte{ulACK}, nil
+	case ulCmdReadCnt:
+		if cmd[1] != 0x02 {
+			return nak, nil
+		}
+		return AppendCRCA([]byte{byte(f.counter), byte(f.counter >> 8), byte(f.counter >> 16)}), nil
+	case ulCmdIncrCnt:
+		f.counter += uint32(cmd[2]) | uint32(cmd[3])<<8 | uint32(cmd[4])<<16
+		return []byte{ulACK}, nil
+	case ulCmdPwdAuth:
+		if !bytes.Equal(cmd[1:5], f.pwd) {
+			return nak, nil
+		}
+		f.authed = true
+		return AppendCRCA(f.pack), nil
+	case ulCmdReadSig:
+		return AppendCRCA(bytes.Repeat([]byte{0x5A}, 32)), nil
+	case ulCmdCheckTearingEvent:
+		return AppendCRCA([]byte{0xBD}), nil
+	}
+	return nil, StatusTimeout
+}
+
+func TestUltralight(t *testing.T) {
+	tag := newFakeNTAG()
+	u := tag.ultralight()
+	if err := u.detect(); err != nil {
+		t.Fatal(err)
+	}
+	if u.Pages != 45 || u.Version.StorageSize != 0x0F {
+		t.Errorf("unexpected pages: %d", u.Pages)
+	}
+
+	data, err := u.Read(43)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data[8:], tag.mem[:8]) {
+		t.Errorf("read does not wrap around: % X", data)
+	}
+	all, err := u.FastRead(0, 44)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(all, tag.mem) {
+		t.Error("unexpected fast read")
+	}
+
+	if err := u.Write(4, []byte{0xDE, 0xAD, 0xBE, 0xEF}); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.CompatWrite(5, append([]byte{0x01, 0x02, 0x03, 0x04}, make([]byte, 12)...)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(tag.mem[16:24], []byte{0xDE, 0xAD, 0xBE, 0xEF, 0x01, 0x02, 0x03, 0x04}) {
+		t.Errorf("unexpected memory: % X", tag.mem[16:24])
+	}
+
+	err = u.Write(0, []byte{0x00, 0x00, 0x00, 0x00})
+	if err != NAKInvalidArgument || tag.reactive != 1 {
+		t.Errorf("unexpected error: %v, reactivated %d times", err, tag.reactive)
+	}
+
+	if err := u.IncrCnt(0x02, 0x010203); err != nil {
+		t.Fatal(err)
+	}
+	if cnt, err := u.ReadCnt(0x02); err != nil || cnt != 0x010203 {
+		t.Errorf("unexpected counter: %#X %v", cnt, err)
+	}
+	if ok, err := u.CheckTearingEvent(0x02); err != nil || !ok {
+		t.Errorf("unexpected tearing flag: %t %v", ok, err)
+	}
+	if sig, err := u.ReadSig(); err != nil || len(sig) != 32 {
+		t.Errorf("unexpected signature: % X %v", sig, err)
+	}
+
+	tag.pwd, tag.pack = []byte{0x12, 0x34, 0x56, 0x78}, []byte{0xAB, 0xCD}
+	if _, err := u.PwdAuth([]byte{0x00, 0x00, 0x00, 0x00}, nil); err != NAKInvalidArgument {
+		t.Errorf("wrong password accepted: %v", err)
+	}
+	if _, err := u.PwdAuth(tag.pwd, []byte{0x00, 0x00}); err != ErrPACKMismatch {
+		t.Errorf("PACK not checked: %v", err)
+	}
+	if pack, err := u.PwdAuth(tag.pwd, []byte{0xAB, 0xCD}); err != nil || !tag.authed {
+		t.Errorf("auth failed: % X %v", pack, err)
+	}
+}
+
+func TestUltralightPages(t *testing.T) {
+	for _, tc := range []struct {
+		v     VersionInfo
+		pages int
+	}{
+		{VersionInfo{ProductType: 0x03, StorageSize: 0x0B}, 20},
+		{VersionInfo{ProductType: 0x03, StorageSize: 0x0E}, 41},
+		{VersionInfo{ProductType: 0x04, StorageSize: 0x11}, 135},
+		{VersionInfo{ProductType: 0x04, StorageSize: 0x13}, 231},
+	} {
+		if pages := ultralightPages(&tc.v); pages != tc.pages {
+			t.Errorf("%+v: %d pages", tc.v, pages)
+		}
+	}
+	if _, err := checkUltralightResp([]byte{0x01, 0x02, 0x03}); err != StatusCRC {
+		t.Errorf("bad CRC accepted: %v", err)
+	}
+	if _, err := checkUltralightResp([]byte{0x04}); err != NAKAuthCounterOverflow {
+		t.Errorf("unexpected NAK: %v", err)
+	}
+}